@@ -0,0 +1,363 @@
+package auditpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/apiserver/audit"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/cluster-authentication-operator/bindata"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
+	observeoauth "github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/oauth"
+)
+
+// knownConditionNames lists all condition types used by this controller.
+// These conditions are operated and defaulted by this controller.
+// Any new condition used by this controller sync() loop should be listed here.
+var knownConditionNames = sets.NewString(
+	"OAuthServerAuditPolicyDegraded",
+)
+
+// extraLabelsPath is the path in unsupportedConfigOverrides.operator to additional
+// labels to set on the audit policy configmap, e.g. for log pipeline selectors
+var extraLabelsPath = []string{"audit", "extraLabels"}
+
+// auditPolicyController applies the audit policy configmap of the oauth-server.
+// The policy is either the operator's default one or the content of the custom
+// policy configmap referenced by the observed config.
+type auditPolicyController struct {
+	operatorClient  v1helpers.OperatorClient
+	configMaps      corev1client.ConfigMapsGetter
+	configMapLister corev1listers.ConfigMapLister
+}
+
+func NewAuditPolicyController(
+	operatorClient v1helpers.OperatorClient,
+	kubeInformersForNamespaces v1helpers.KubeInformersForNamespaces,
+	configMaps corev1client.ConfigMapsGetter,
+	recorder events.Recorder,
+) factory.Controller {
+	c := &auditPolicyController{
+		operatorClient:  operatorClient,
+		configMaps:      configMaps,
+		configMapLister: kubeInformersForNamespaces.ConfigMapLister(),
+	}
+
+	return factory.New().WithInformers(
+		operatorClient.Informer(),
+		kubeInformersForNamespaces.InformersFor("openshift-config").Core().V1().ConfigMaps().Informer(),
+		kubeInformersForNamespaces.InformersFor("openshift-authentication").Core().V1().ConfigMaps().Informer(),
+	).ResyncEvery(wait.Jitter(time.Minute, 1.0)).WithSync(c.sync).ToController("OAuthServerAuditPolicy", recorder.WithComponentSuffix("oauth-server-audit-policy-controller"))
+}
+
+func (c *auditPolicyController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	operatorSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+
+	foundConditions := []operatorv1.OperatorCondition{}
+
+	expected, err := c.expectedAuditPolicyConfigMap(operatorSpec)
+	if err != nil {
+		foundConditions = append(foundConditions, operatorv1.OperatorCondition{
+			Type:    "OAuthServerAuditPolicyDegraded",
+			Status:  operatorv1.ConditionTrue,
+			Reason:  "InvalidPolicy",
+			Message: err.Error(),
+		})
+	} else if expected != nil {
+		if err := c.applyAuditPolicyConfigMap(ctx, syncCtx.Recorder(), expected); err != nil {
+			foundConditions = append(foundConditions, operatorv1.OperatorCondition{
+				Type:    "OAuthServerAuditPolicyDegraded",
+				Status:  operatorv1.ConditionTrue,
+				Reason:  "ApplyFailed",
+				Message: fmt.Sprintf("Failed to apply the audit policy configmap: %v", err),
+			})
+		}
+	}
+
+	return common.UpdateControllerConditions(ctx, c.operatorClient, knownConditionNames, foundConditions)
+}
+
+// applyAuditPolicyConfigMap applies the audit policy configmap. Concurrent
+// reconciles, e.g. by an operator replica that has not yet noticed it lost the
+// leader lease, race on the configmap's creation and resourceVersion. Losing
+// such a race is retried against the fresh state, which makes the apply
+// converge to the same configmap instead of degrading the operator.
+func (c *auditPolicyController) applyAuditPolicyConfigMap(ctx context.Context, recorder events.Recorder, expected *corev1.ConfigMap) error {
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+	}, func() error {
+		_, _, err := resourceapply.ApplyConfigMap(ctx, c.configMaps, recorder, expected)
+		return err
+	})
+}
+
+// expectedAuditPolicyConfigMap returns the audit policy configmap to apply, or
+// nil when the configmap is not managed by the operator.
+func (c *auditPolicyController) expectedAuditPolicyConfigMap(operatorSpec *operatorv1.OperatorSpec) (*corev1.ConfigMap, error) {
+	unsupportedConfig, err := common.UnsupportedConfigOverrides(operatorSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode unsupportedConfigOverrides: %w", err)
+	}
+
+	managed, err := observeoauth.AuditConfigMapManaged(unsupportedConfig)
+	if err != nil || !managed {
+		return nil, err
+	}
+
+	expected := resourceread.ReadConfigMapV1OrDie(bindata.MustAsset("oauth-openshift/audit-policy.yaml"))
+
+	if err := applyExtraLabels(expected, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	observedConfig, err := observedOAuthServerConfig(operatorSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	customPolicyConfigMap, _, err := unstructured.NestedString(observedConfig, observeoauth.ObservedAuditPolicyConfigMapPath...)
+	if err != nil {
+		return nil, err
+	}
+	if len(customPolicyConfigMap) == 0 {
+		policy, err := profileAuditPolicy(observedConfig)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			expected.Data = map[string]string{
+				observeoauth.AuditPolicyKey: string(policy),
+			}
+		}
+		if err := injectAuthorizationAuditRule(expected, observedConfig); err != nil {
+			return nil, err
+		}
+		if err := includeRequestReceivedStage(expected, observedConfig); err != nil {
+			return nil, err
+		}
+		return expected, nil
+	}
+
+	// the content is validated again as it might have changed since it was observed
+	source, err := c.configMapLister.ConfigMaps("openshift-config").Get(customPolicyConfigMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the custom audit policy configmap openshift-config/%s: %w", customPolicyConfigMap, err)
+	}
+
+	policy, ok := source.Data[observeoauth.AuditPolicyKey]
+	if !ok {
+		return nil, fmt.Errorf("the custom audit policy configmap openshift-config/%s is missing the %q key", customPolicyConfigMap, observeoauth.AuditPolicyKey)
+	}
+	if _, err := observeoauth.ParseAuditPolicy([]byte(policy)); err != nil {
+		return nil, fmt.Errorf("the custom audit policy configmap openshift-config/%s does not contain a valid policy: %w", customPolicyConfigMap, err)
+	}
+
+	expected.Data = map[string]string{
+		observeoauth.AuditPolicyKey: policy,
+	}
+
+	return expected, nil
+}
+
+// applyExtraLabels adds the labels configured in unsupportedConfigOverrides.operator to
+// the audit policy configmap. Labels managed by the operator take precedence.
+func applyExtraLabels(cm *corev1.ConfigMap, unsupportedConfig map[string]interface{}) error {
+	extraLabels, _, err := unstructured.NestedStringMap(unsupportedConfig, extraLabelsPath...)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", strings.Join(extraLabelsPath, "."), err)
+	}
+
+	if len(extraLabels) == 0 {
+		return nil
+	}
+
+	if cm.Labels == nil {
+		cm.Labels = map[string]string{}
+	}
+	for k, v := range extraLabels {
+		if _, managed := cm.Labels[k]; managed {
+			continue
+		}
+		cm.Labels[k] = v
+	}
+
+	return nil
+}
+
+// renderAuditPolicyConfigMap serializes the audit policy configmap to YAML with
+// a stable field order so that it can be compared against golden files.
+func renderAuditPolicyConfigMap(cm *corev1.ConfigMap) ([]byte, error) {
+	rendered := cm.DeepCopy()
+	rendered.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+	// yaml.Marshal goes through JSON which sorts the keys of all maps
+	return yaml.Marshal(rendered)
+}
+
+// injectAuthorizationAuditRule makes the audit policy in the configmap capture
+// authorization decisions at the level recorded in the observed config. The
+// rule goes first as the first matching rule of a policy applies.
+func injectAuthorizationAuditRule(cm *corev1.ConfigMap, observedConfig map[string]interface{}) error {
+	level, _, err := unstructured.NestedString(observedConfig, observeoauth.ObservedAuditAuthorizationLevelPath...)
+	if err != nil || len(level) == 0 {
+		return err
+	}
+
+	return updateAuditPolicy(cm, func(policy *auditv1.Policy) {
+		policy.Rules = append([]auditv1.PolicyRule{observeoauth.AuthorizationAuditRule(auditv1.Level(level))}, policy.Rules...)
+	})
+}
+
+// includeRequestReceivedStage makes the audit policy in the configmap generate
+// events in the RequestReceived stage, too, when it is recorded in the observed
+// config. The stage is omitted by both the policy and some of its rules.
+func includeRequestReceivedStage(cm *corev1.ConfigMap, observedConfig map[string]interface{}) error {
+	include, _, err := unstructured.NestedBool(observedConfig, observeoauth.ObservedAuditIncludeRequestReceivedPath...)
+	if err != nil || !include {
+		return err
+	}
+
+	return updateAuditPolicy(cm, func(policy *auditv1.Policy) {
+		policy.OmitStages = withoutStage(policy.OmitStages, auditv1.StageRequestReceived)
+		for i := range policy.Rules {
+			policy.Rules[i].OmitStages = withoutStage(policy.Rules[i].OmitStages, auditv1.StageRequestReceived)
+		}
+	})
+}
+
+func withoutStage(stages []auditv1.Stage, stage auditv1.Stage) []auditv1.Stage {
+	var ret []auditv1.Stage
+	for _, s := range stages {
+		if s != stage {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// updateAuditPolicy parses the audit policy in the configmap, modifies it and
+// writes it back
+func updateAuditPolicy(cm *corev1.ConfigMap, modify func(policy *auditv1.Policy)) error {
+	policy, err := observeoauth.ParseAuditPolicy([]byte(cm.Data[observeoauth.AuditPolicyKey]))
+	if err != nil {
+		return fmt.Errorf("failed to parse the audit policy: %w", err)
+	}
+	modify(policy)
+	policy.Kind = "Policy"
+	policy.APIVersion = auditv1.SchemeGroupVersion.String()
+
+	rendered, err := yaml.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	cm.Data[observeoauth.AuditPolicyKey] = string(rendered)
+	return nil
+}
+
+// nonResourceVerbs pairs the verbs of write requests to resources with the
+// verbs of the equivalent requests to non-resource URLs. It is ordered to keep
+// the generated policy stable.
+var nonResourceVerbs = [][2]string{
+	{"create", "post"},
+	{"update", "put"},
+}
+
+// addNonResourceVerbs extends the write verbs of the policy rules generated for
+// the kube-apiserver to requests to non-resource URLs, whose verbs are the
+// lowercase HTTP methods. The oauth-server only serves non-resource URLs, so
+// without them e.g. the login requests would never match a write rule.
+func addNonResourceVerbs(policy *auditv1.Policy) {
+	for i := range policy.Rules {
+		verbs := sets.NewString(policy.Rules[i].Verbs...)
+		for _, pair := range nonResourceVerbs {
+			resourceVerb, nonResourceVerb := pair[0], pair[1]
+			if verbs.Has(resourceVerb) && !verbs.Has(nonResourceVerb) {
+				verbs.Insert(nonResourceVerb)
+				policy.Rules[i].Verbs = append(policy.Rules[i].Verbs, nonResourceVerb)
+			}
+		}
+	}
+}
+
+// observedOAuthServerConfig returns the observed config of the oauth-server
+func observedOAuthServerConfig(operatorSpec *operatorv1.OperatorSpec) (map[string]interface{}, error) {
+	observedConfigBytes, err := common.UnstructuredConfigFrom(operatorSpec.ObservedConfig.Raw, configobservation.OAuthServerConfigPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the observed config: %w", err)
+	}
+
+	observedConfig := map[string]interface{}{}
+	if err := json.Unmarshal(observedConfigBytes, &observedConfig); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the observed config: %w", err)
+	}
+
+	return observedConfig, nil
+}
+
+// profileAuditPolicy generates the audit policy for the audit profile and the
+// per-group custom rules recorded in the observed config. No policy is
+// returned when none are recorded, in which case the operator's default
+// audit policy applies.
+func profileAuditPolicy(observedConfig map[string]interface{}) ([]byte, error) {
+	profile, _, err := unstructured.NestedString(observedConfig, observeoauth.ObservedAuditProfilePath...)
+	if err != nil || len(profile) == 0 {
+		return nil, err
+	}
+
+	auditConfig := configv1.Audit{Profile: configv1.AuditProfileType(profile)}
+
+	customRules, _, err := unstructured.NestedSlice(observedConfig, observeoauth.ObservedAuditCustomRulesPath...)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range customRules {
+		ruleMap, ok := rule.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected audit custom rule %v", rule)
+		}
+		group, _, _ := unstructured.NestedString(ruleMap, "group")
+		ruleProfile, _, _ := unstructured.NestedString(ruleMap, "profile")
+		auditConfig.CustomRules = append(auditConfig.CustomRules, configv1.AuditCustomRule{
+			Group:   group,
+			Profile: configv1.AuditProfileType(ruleProfile),
+		})
+	}
+
+	policy, err := audit.GetAuditPolicy(auditConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the audit policy for profile %q: %w", profile, err)
+	}
+
+	// the policy must not be modified without a copy
+	policy = policy.DeepCopy()
+	policy.Kind = "Policy"
+	policy.APIVersion = auditv1.SchemeGroupVersion.String()
+	addNonResourceVerbs(policy)
+
+	return yaml.Marshal(policy)
+}
@@ -0,0 +1,478 @@
+package auditpolicy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/apis/audit"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	auditpolicy "k8s.io/apiserver/pkg/audit/policy"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	observeoauth "github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/oauth"
+)
+
+func TestExpectedAuditPolicyConfigMap(t *testing.T) {
+	const validPolicy = "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n"
+
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		observedConfig    string
+		policy            string
+		expectedPolicy    string
+		expectedRules     int
+		expectDefault     bool
+		expectNil         bool
+		expectErr         bool
+	}{
+		{
+			name:          "default policy",
+			expectDefault: true,
+		},
+		{
+			name:              "unmanaged configmap",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false}}}`,
+			expectNil:         true,
+		},
+		{
+			name:           "custom policy",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"configMap":"custom"}}}`,
+			policy:         validPolicy,
+			expectedPolicy: validPolicy,
+		},
+		{
+			name:           "custom policy became invalid",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"configMap":"custom"}}}`,
+			policy:         "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules: not-a-list\n",
+			expectErr:      true,
+		},
+		{
+			name:           "custom policy takes precedence over the audit profile",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"configMap":"custom","profile":"AllRequestBodies"}}}`,
+			policy:         validPolicy,
+			expectedPolicy: validPolicy,
+		},
+		{
+			name:           "WriteRequestBodies profile",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"WriteRequestBodies"}}}`,
+			expectedRules:  6,
+		},
+		{
+			name:           "AllRequestBodies profile",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"AllRequestBodies"}}}`,
+			expectedRules:  5,
+		},
+		{
+			name:           "custom rules",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"Default","customRules":[{"group":"system:authenticated:oauth","profile":"AllRequestBodies"}]}}}`,
+			expectedRules:  7,
+		},
+		{
+			name:           "unknown audit profile",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"Everything"}}}`,
+			expectErr:      true,
+		},
+		{
+			name:           "custom policy missing",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"configMap":"missing"}}}`,
+			policy:         validPolicy,
+			expectErr:      true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom", Namespace: "openshift-config"},
+				Data:       map[string]string{"audit.yaml": tt.policy},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &auditPolicyController{configMapLister: corev1listers.NewConfigMapLister(indexer)}
+
+			operatorSpec := &operatorv1.OperatorSpec{
+				UnsupportedConfigOverrides: runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)},
+				ObservedConfig:             runtime.RawExtension{Raw: []byte(tt.observedConfig)},
+			}
+
+			got, err := c.expectedAuditPolicyConfigMap(operatorSpec)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if tt.expectNil {
+				if got != nil {
+					t.Fatalf("expected no configmap, got %v", got)
+				}
+				return
+			}
+
+			if got.Namespace != "openshift-authentication" || got.Name != "audit" {
+				t.Errorf("unexpected configmap %s/%s", got.Namespace, got.Name)
+			}
+
+			if tt.expectDefault {
+				if len(got.Data["audit.yaml"]) == 0 {
+					t.Errorf("expected the default policy to be set")
+				}
+				return
+			}
+
+			if tt.expectedRules > 0 {
+				policy, err := observeoauth.ParseAuditPolicy([]byte(got.Data["audit.yaml"]))
+				if err != nil {
+					t.Fatalf("expected a valid policy, got %v", err)
+				}
+				if len(policy.Rules) != tt.expectedRules {
+					t.Errorf("expected %d policy rules, got %d", tt.expectedRules, len(policy.Rules))
+				}
+				return
+			}
+
+			if got.Data["audit.yaml"] != tt.expectedPolicy {
+				t.Errorf("expected policy %q, got %q", tt.expectedPolicy, got.Data["audit.yaml"])
+			}
+		})
+	}
+}
+
+func TestExpectedAuditPolicyConfigMapExtraLabels(t *testing.T) {
+	c := &auditPolicyController{configMapLister: corev1listers.NewConfigMapLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))}
+
+	operatorSpec := &operatorv1.OperatorSpec{
+		UnsupportedConfigOverrides: runtime.RawExtension{
+			Raw: []byte(`{"operator":{"audit":{"extraLabels":{"logging.example.com/pipeline":"loki","app":"overridden"}}}}`),
+		},
+	}
+
+	got, err := c.expectedAuditPolicyConfigMap(operatorSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLabels := map[string]string{
+		"app":                          "oauth-openshift",
+		"logging.example.com/pipeline": "loki",
+	}
+	if !reflect.DeepEqual(expectedLabels, got.Labels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, got.Labels)
+	}
+}
+
+func TestAuditPolicyConfigMapGolden(t *testing.T) {
+	const customPolicy = "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n"
+
+	for _, tt := range []struct {
+		name           string
+		observedConfig string
+		goldenFile     string
+	}{
+		{
+			name:       "default policy",
+			goldenFile: "./testdata/default.yaml",
+		},
+		{
+			name:           "custom policy",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"configMap":"custom"}}}`,
+			goldenFile:     "./testdata/custom.yaml",
+		},
+		{
+			name:           "WriteRequestBodies profile",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"WriteRequestBodies"}}}`,
+			goldenFile:     "./testdata/writerequestbodies.yaml",
+		},
+		{
+			name:           "AllRequestBodies profile",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"AllRequestBodies"}}}`,
+			goldenFile:     "./testdata/allrequestbodies.yaml",
+		},
+		{
+			name:           "None profile",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"None"}}}`,
+			goldenFile:     "./testdata/none.yaml",
+		},
+		{
+			name:           "custom rules",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"Default","customRules":[{"group":"system:authenticated:oauth","profile":"AllRequestBodies"},{"group":"system:serviceaccounts","profile":"None"}]}}}`,
+			goldenFile:     "./testdata/customrules.yaml",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom", Namespace: "openshift-config"},
+				Data:       map[string]string{"audit.yaml": customPolicy},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &auditPolicyController{configMapLister: corev1listers.NewConfigMapLister(indexer)}
+
+			cm, err := c.expectedAuditPolicyConfigMap(&operatorv1.OperatorSpec{
+				ObservedConfig: runtime.RawExtension{Raw: []byte(tt.observedConfig)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rendered, err := renderAuditPolicyConfigMap(cm)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden, err := os.ReadFile(tt.goldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(string(golden), string(rendered)); diff != "" {
+				t.Errorf("rendered audit policy configmap differs from %s: %s", tt.goldenFile, diff)
+			}
+		})
+	}
+}
+
+func TestApplyAuditPolicyConfigMapConcurrently(t *testing.T) {
+	c := &auditPolicyController{configMapLister: corev1listers.NewConfigMapLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))}
+	expected, err := c.expectedAuditPolicyConfigMap(&operatorv1.OperatorSpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := expected.DeepCopy()
+	stale.Data = map[string]string{"audit.yaml": "stale"}
+
+	for _, tt := range []struct {
+		name     string
+		existing []runtime.Object
+	}{
+		{
+			name: "racing creates",
+		},
+		{
+			name:     "racing updates",
+			existing: []runtime.Object{stale},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(tt.existing...)
+
+			// the first write loses the race against another replica
+			var lostRace int32
+			kubeClient.PrependReactor("*", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				if action.GetVerb() != "create" && action.GetVerb() != "update" {
+					return false, nil, nil
+				}
+				if !atomic.CompareAndSwapInt32(&lostRace, 0, 1) {
+					return false, nil, nil
+				}
+				if action.GetVerb() == "create" {
+					return true, nil, errors.NewAlreadyExists(corev1.Resource("configmaps"), "audit")
+				}
+				return true, nil, errors.NewConflict(corev1.Resource("configmaps"), "audit", fmt.Errorf("the object has been modified"))
+			})
+			c.configMaps = kubeClient.CoreV1()
+
+			const replicas = 5
+			errs := make(chan error, replicas)
+			var wg sync.WaitGroup
+			for i := 0; i < replicas; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- c.applyAuditPolicyConfigMap(context.TODO(), events.NewInMemoryRecorder(t.Name()), expected)
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+
+			got, err := kubeClient.CoreV1().ConfigMaps("openshift-authentication").Get(context.TODO(), "audit", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expected.Data, got.Data) {
+				t.Errorf("expected the configmap to converge to %v, got %v", expected.Data, got.Data)
+			}
+		})
+	}
+}
+
+func TestAuthorizationAuditRule(t *testing.T) {
+	// requests as seen by the audit filter of the oauth-server
+	anonymous := &user.DefaultInfo{Name: "system:anonymous"}
+	authorize := authorizer.AttributesRecord{User: anonymous, Verb: "get", Path: "/oauth/authorize"}
+	approve := authorizer.AttributesRecord{User: anonymous, Verb: "post", Path: "/oauth/authorize/approve"}
+	token := authorizer.AttributesRecord{User: anonymous, Verb: "post", Path: "/oauth/token"}
+	tokenRequest := authorizer.AttributesRecord{User: anonymous, Verb: "get", Path: "/oauth/token/request"}
+	healthz := authorizer.AttributesRecord{Verb: "get", Path: "/healthz"}
+
+	for _, tt := range []struct {
+		name           string
+		observedConfig string
+		expectedRules  int
+		expectedLevels map[authorizer.AttributesRecord]audit.Level
+	}{
+		{
+			name:           "default policy",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"authorizationDecisionsLevel":"RequestResponse"}}}`,
+			expectedRules:  3,
+			expectedLevels: map[authorizer.AttributesRecord]audit.Level{
+				authorize:    audit.LevelRequestResponse,
+				approve:      audit.LevelRequestResponse,
+				token:        audit.LevelRequestResponse,
+				tokenRequest: audit.LevelMetadata,
+				healthz:      audit.LevelNone,
+			},
+		},
+		{
+			name:           "profile policy",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"WriteRequestBodies","authorizationDecisionsLevel":"Request"}}}`,
+			expectedRules:  7,
+			expectedLevels: map[authorizer.AttributesRecord]audit.Level{
+				authorize:    audit.LevelRequest,
+				approve:      audit.LevelRequest,
+				token:        audit.LevelRequest,
+				tokenRequest: audit.LevelMetadata,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &auditPolicyController{configMapLister: corev1listers.NewConfigMapLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))}
+
+			operatorSpec := &operatorv1.OperatorSpec{
+				ObservedConfig: runtime.RawExtension{Raw: []byte(tt.observedConfig)},
+			}
+			observedConfig, err := observedOAuthServerConfig(operatorSpec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			level, _, err := unstructured.NestedString(observedConfig, observeoauth.ObservedAuditAuthorizationLevelPath...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := c.expectedAuditPolicyConfigMap(operatorSpec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			policy, err := observeoauth.ParseAuditPolicy([]byte(got.Data["audit.yaml"]))
+			if err != nil {
+				t.Fatalf("expected a valid policy, got %v", err)
+			}
+			if len(policy.Rules) != tt.expectedRules {
+				t.Errorf("expected %d policy rules, got %d", tt.expectedRules, len(policy.Rules))
+			}
+
+			expectedRule := observeoauth.AuthorizationAuditRule(auditv1.Level(level))
+			if diff := cmp.Diff(expectedRule, policy.Rules[0]); diff != "" {
+				t.Errorf("expected the authorization rule to go first (-want +got):\n%s", diff)
+			}
+
+			// load the policy the way the oauth-server does
+			loaded, err := auditpolicy.LoadPolicyFromBytes([]byte(got.Data["audit.yaml"]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			evaluator := auditpolicy.NewPolicyRuleEvaluator(loaded)
+			for attrs, expected := range tt.expectedLevels {
+				if got := evaluator.EvaluatePolicyRule(attrs).Level; got != expected {
+					t.Errorf("expected %s %s to be audited at level %s, got %s", attrs.Verb, attrs.Path, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestReceivedStage(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		observedConfig string
+		expectOmitted  bool
+	}{
+		{
+			name:          "default policy omits the stage",
+			expectOmitted: true,
+		},
+		{
+			name:           "profile policy omits the stage",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"WriteRequestBodies"}}}`,
+			expectOmitted:  true,
+		},
+		{
+			name:           "default policy includes the stage",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"includeRequestReceivedStage":true}}}`,
+		},
+		{
+			name:           "profile policy includes the stage",
+			observedConfig: `{"oauthServer":{"auditPolicy":{"profile":"WriteRequestBodies","includeRequestReceivedStage":true}}}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &auditPolicyController{configMapLister: corev1listers.NewConfigMapLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))}
+
+			got, err := c.expectedAuditPolicyConfigMap(&operatorv1.OperatorSpec{
+				ObservedConfig: runtime.RawExtension{Raw: []byte(tt.observedConfig)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			policy, err := observeoauth.ParseAuditPolicy([]byte(got.Data["audit.yaml"]))
+			if err != nil {
+				t.Fatalf("expected a valid policy, got %v", err)
+			}
+
+			omittedByPolicy := false
+			for _, stage := range policy.OmitStages {
+				if stage == auditv1.StageRequestReceived {
+					omittedByPolicy = true
+				}
+			}
+			if omittedByPolicy != tt.expectOmitted {
+				t.Errorf("expected the policy to omit the RequestReceived stage: %t, got omitStages %v", tt.expectOmitted, policy.OmitStages)
+			}
+
+			if tt.expectOmitted {
+				return
+			}
+			for i, rule := range policy.Rules {
+				for _, stage := range rule.OmitStages {
+					if stage == auditv1.StageRequestReceived {
+						t.Errorf("expected rule %d to not omit the RequestReceived stage", i)
+					}
+				}
+			}
+		})
+	}
+}
@@ -7,10 +7,17 @@ import (
 	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 var (
 	shellEscapePattern = regexp.MustCompile(`[^\w@%+=:,./-]`)
+
+	// MultiValueArguments are the flags of the oauth-server that may be passed
+	// several times, with all of their values taking effect. None of the flags
+	// the oauth-server accepts (the config, logging and generic apiserver audit
+	// flags) is repeatable today, so every flag is single-valued for now.
+	MultiValueArguments = sets.NewString()
 )
 
 // ServerArguments is a simple abstraction to flags / options that can be used
@@ -46,6 +53,58 @@ func Parse(raw map[string]interface{}) (ServerArguments, error) {
 	return args, nil
 }
 
+// MergeServerArguments merges the src ServerArguments into dst. The values of
+// MultiValueArguments are concatenated, the ones of dst first, and repeated
+// values are kept as the flag is passed once per value. Any other flag set in
+// both with different values is a conflict, for which an error listing all of
+// the conflicting flags is returned and dst is left untouched.
+//
+// The observed config of every observer is merged by the library-go config
+// observer, which knows nothing about flags. ObserveAudit is the only observer
+// contributing serverArguments so far, hence nothing calls this yet. Observers
+// contributing serverArguments next to it have to merge them with this.
+func MergeServerArguments(dst, src ServerArguments) error {
+	merged := make(ServerArguments, len(dst)+len(src))
+	for name, values := range dst {
+		merged[name] = values
+	}
+
+	conflicts := []string{}
+	for name, srcValues := range src {
+		dstValues, found := dst[name]
+		switch {
+		case !found:
+			merged[name] = append([]string(nil), srcValues...)
+		case MultiValueArguments.Has(name):
+			merged[name] = append(append([]string(nil), dstValues...), srcValues...)
+		case !equalValues(dstValues, srcValues):
+			conflicts = append(conflicts, fmt.Sprintf("%s (%q != %q)", name, dstValues, srcValues))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("conflicting server arguments: %s", strings.Join(conflicts, ", "))
+	}
+
+	for name, values := range merged {
+		dst[name] = values
+	}
+	return nil
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // shellEscape returns a shell-escaped version of the string s. The returned value
 // is a string that can safely be used as one token in a shell command line.
 //
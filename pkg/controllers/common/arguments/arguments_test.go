@@ -0,0 +1,107 @@
+package arguments
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeServerArguments(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		dst           ServerArguments
+		src           ServerArguments
+		expected      ServerArguments
+		expectedError string
+	}{
+		{
+			name: "union of distinct flags",
+			dst: ServerArguments{
+				"audit-log-path": {"/var/log/oauth-server/audit.log"},
+			},
+			src: ServerArguments{
+				"audit-log-format": {"json"},
+			},
+			expected: ServerArguments{
+				"audit-log-path":   {"/var/log/oauth-server/audit.log"},
+				"audit-log-format": {"json"},
+			},
+		},
+		{
+			name: "equal single-value flags",
+			dst: ServerArguments{
+				"audit-log-format": {"json"},
+			},
+			src: ServerArguments{
+				"audit-log-format": {"json"},
+			},
+			expected: ServerArguments{
+				"audit-log-format": {"json"},
+			},
+		},
+		{
+			name: "multi-value flags are concatenated",
+			dst: ServerArguments{
+				"test-multi-value": {"a", "b"},
+			},
+			src: ServerArguments{
+				"test-multi-value": {"b", "c"},
+			},
+			expected: ServerArguments{
+				"test-multi-value": {"a", "b", "b", "c"},
+			},
+		},
+		{
+			name: "conflicting single-value flags",
+			dst: ServerArguments{
+				"audit-log-format":  {"json"},
+				"audit-log-maxsize": {"100"},
+			},
+			src: ServerArguments{
+				"audit-log-format":  {"legacy"},
+				"audit-log-maxsize": {"200"},
+			},
+			expectedError: `conflicting server arguments: audit-log-format (["json"] != ["legacy"]), audit-log-maxsize (["100"] != ["200"])`,
+		},
+		{
+			name: "conflicts leave dst untouched",
+			dst: ServerArguments{
+				"audit-log-format": {"json"},
+			},
+			src: ServerArguments{
+				"audit-log-format": {"legacy"},
+				"audit-log-path":   {"/var/log/oauth-server/audit.log"},
+			},
+			expected: ServerArguments{
+				"audit-log-format": {"json"},
+			},
+			expectedError: `conflicting server arguments: audit-log-format (["json"] != ["legacy"])`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// none of the oauth-server flags is repeatable, use a made up one
+			MultiValueArguments.Insert("test-multi-value")
+			defer MultiValueArguments.Delete("test-multi-value")
+
+			err := MergeServerArguments(tt.dst, tt.src)
+			if len(tt.expectedError) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("expected error %q, got %v", tt.expectedError, err)
+				}
+				if tt.expected != nil {
+					if diff := cmp.Diff(tt.expected, tt.dst); diff != "" {
+						t.Errorf("unexpected dst after a conflict (-want +got):\n%s", diff)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, tt.dst); diff != "" {
+				t.Errorf("unexpected merge result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
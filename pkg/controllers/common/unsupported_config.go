@@ -0,0 +1,50 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	kyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+)
+
+// UnsupportedOperatorConfigPrefix is the key in unsupportedConfigOverrides
+// under which the knobs of the operator itself (audit, deployment,
+// networkPolicy, session, ...) live, as opposed to the raw oauthServer and
+// oauthAPIServer configs which are merged into the operands' configs as is.
+const UnsupportedOperatorConfigPrefix = "operator"
+
+// UnsupportedConfigOverrides decodes the operator section of the operator's
+// unsupportedConfigOverrides field (see UnsupportedOperatorConfigPrefix) into
+// an unstructured map. An empty map is returned when the section is unset.
+func UnsupportedConfigOverrides(spec *operatorv1.OperatorSpec) (map[string]interface{}, error) {
+	if spec == nil || len(spec.UnsupportedConfigOverrides.Raw) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	configJson, err := kyaml.ToJSON(spec.UnsupportedConfigOverrides.Raw)
+	if err != nil {
+		klog.Warning(err)
+		// maybe it's just json
+		configJson = spec.UnsupportedConfigOverrides.Raw
+	}
+
+	unsupportedConfig := map[string]interface{}{}
+	if err := json.NewDecoder(bytes.NewBuffer(configJson)).Decode(&unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	operatorConfig, found := unsupportedConfig[UnsupportedOperatorConfigPrefix]
+	if !found || operatorConfig == nil {
+		return map[string]interface{}{}, nil
+	}
+	operatorConfigMap, ok := operatorConfig.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s in unsupportedConfigOverrides must be an object, got %T", UnsupportedOperatorConfigPrefix, operatorConfig)
+	}
+
+	return operatorConfigMap, nil
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestUnsupportedConfigOverrides(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		expected          map[string]interface{}
+		expectErr         bool
+	}{
+		{
+			name:     "unset",
+			expected: map[string]interface{}{},
+		},
+		{
+			name:              "only the operator section is returned",
+			unsupportedConfig: `{"oauthServer":{"oauthConfig":{}},"operator":{"session":{"maxAge":"10m"}}}`,
+			expected: map[string]interface{}{
+				"session": map[string]interface{}{"maxAge": "10m"},
+			},
+		},
+		{
+			name:              "yaml",
+			unsupportedConfig: "operator:\n  audit:\n    complianceMode: true\n",
+			expected: map[string]interface{}{
+				"audit": map[string]interface{}{"complianceMode": true},
+			},
+		},
+		{
+			name:              "top-level knobs are not part of the operator section",
+			unsupportedConfig: `{"session":{"maxAge":"10m"}}`,
+			expected:          map[string]interface{}{},
+		},
+		{
+			name:              "operator section is not an object",
+			unsupportedConfig: `{"operator":"audit"}`,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &operatorv1.OperatorSpec{
+				UnsupportedConfigOverrides: runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)},
+			}
+
+			got, err := UnsupportedConfigOverrides(spec)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+
+			if !tt.expectErr && !cmp.Equal(tt.expected, got) {
+				t.Errorf("unexpected config: %s", cmp.Diff(tt.expected, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+)
+
+// ConfigWarnings collects non-fatal configuration problems, e.g. unknown or
+// bypassed settings, so that they can be surfaced in the operator status.
+// Warning events alone are easily missed and expire after a while.
+type ConfigWarnings struct {
+	warnings []string
+}
+
+// Warnf records a warning
+func (w *ConfigWarnings) Warnf(format string, args ...interface{}) {
+	w.warnings = append(w.warnings, fmt.Sprintf(format, args...))
+}
+
+// Len returns the number of recorded warnings
+func (w *ConfigWarnings) Len() int {
+	return len(w.warnings)
+}
+
+// ToCondition returns an informational condition of the given type that is
+// True and lists the warnings while there are any, and False otherwise.
+func (w *ConfigWarnings) ToCondition(conditionType string) operatorv1.OperatorCondition {
+	if w.Len() == 0 {
+		return operatorv1.OperatorCondition{
+			Type:   conditionType,
+			Status: operatorv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+
+	warnings := append([]string{}, w.warnings...)
+	sort.Strings(warnings)
+	return operatorv1.OperatorCondition{
+		Type:    conditionType,
+		Status:  operatorv1.ConditionTrue,
+		Reason:  "ConfigWarnings",
+		Message: strings.Join(warnings, "\n"),
+	}
+}
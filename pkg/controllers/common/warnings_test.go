@@ -0,0 +1,46 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	operatorv1 "github.com/openshift/api/operator/v1"
+)
+
+func TestConfigWarningsToCondition(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		warnings          []string
+		expectedCondition operatorv1.OperatorCondition
+	}{
+		{
+			name: "no warnings",
+			expectedCondition: operatorv1.OperatorCondition{
+				Type:   "TestConfigWarning",
+				Status: operatorv1.ConditionFalse,
+				Reason: "AsExpected",
+			},
+		},
+		{
+			name:     "sorted warnings",
+			warnings: []string{"second", "first"},
+			expectedCondition: operatorv1.OperatorCondition{
+				Type:    "TestConfigWarning",
+				Status:  operatorv1.ConditionTrue,
+				Reason:  "ConfigWarnings",
+				Message: "first\nsecond",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := &ConfigWarnings{}
+			for _, w := range tt.warnings {
+				warnings.Warnf("%s", w)
+			}
+
+			if diff := cmp.Diff(tt.expectedCondition, warnings.ToCondition("TestConfigWarning")); diff != "" {
+				t.Errorf("unexpected condition (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	configinformers "github.com/openshift/client-go/config/informers/externalversions"
+	operatorinformers "github.com/openshift/client-go/operator/informers/externalversions"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/configobserver"
 	"github.com/openshift/library-go/pkg/operator/configobserver/apiserver"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/console"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/dns"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/infrastructure"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/oauth"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/routersecret"
@@ -24,6 +26,7 @@ func NewConfigObserver(
 	operatorClient v1helpers.OperatorClient,
 	kubeInformersForNamespaces v1helpers.KubeInformersForNamespaces,
 	configInformer configinformers.SharedInformerFactory,
+	operatorInformer operatorinformers.SharedInformerFactory,
 	resourceSyncer resourcesynccontroller.ResourceSyncer,
 	enabledClusterCapabilities sets.String,
 	eventRecorder events.Recorder,
@@ -41,6 +44,8 @@ func NewConfigObserver(
 		configInformer.Config().V1().OAuths().Informer().HasSynced,
 		configInformer.Config().V1().Ingresses().Informer().HasSynced,
 		configInformer.Config().V1().ClusterVersions().Informer().HasSynced,
+		operatorInformer.Operator().V1().DNSes().Informer().HasSynced,
+		operatorInformer.Operator().V1().Authentications().Informer().HasSynced,
 	}
 
 	informers := []factory.Informer{
@@ -50,6 +55,9 @@ func NewConfigObserver(
 		configInformer.Config().V1().OAuths().Informer(),
 		configInformer.Config().V1().Ingresses().Informer(),
 		configInformer.Config().V1().ClusterVersions().Informer(),
+		operatorInformer.Operator().V1().DNSes().Informer(),
+		// unsupportedConfigOverrides of the operator config drive the audit observation
+		operatorInformer.Operator().V1().Authentications().Informer(),
 	}
 
 	for _, ns := range interestingNamespaces {
@@ -69,9 +77,11 @@ func NewConfigObserver(
 		apiserver.ObserveAdditionalCORSAllowedOrigins,
 		apiserver.ObserveTLSSecurityProfile,
 		infrastructure.ObserveAPIServerURL,
+		dns.ObserveMasterURL,
 		oauth.ObserveIdentityProviders,
 		oauth.ObserveTemplates,
 		oauth.ObserveTokenConfig,
+		oauth.ObserveSessionConfig,
 		oauth.ObserveAudit,
 		configobserveroauth.ObserveAccessTokenInactivityTimeout,
 		routersecret.ObserveRouterSecret,
@@ -89,8 +99,12 @@ func NewConfigObserver(
 		ClusterVersionLister: configInformer.Config().V1().ClusterVersions().Lister(),
 		InfrastructureLister: configInformer.Config().V1().Infrastructures().Lister(),
 		OAuthLister_:         configInformer.Config().V1().OAuths().Lister(),
-		ResourceSync:         resourceSyncer,
-		PreRunCachesSynced:   preRunCacheSynced,
+
+		AuthenticationOperatorLister: operatorInformer.Operator().V1().Authentications().Lister(),
+		DNSLister:                    operatorInformer.Operator().V1().DNSes().Lister(),
+
+		ResourceSync:       resourceSyncer,
+		PreRunCachesSynced: preRunCacheSynced,
 	}
 
 	// Check if the Console capability is enabled on the cluster and sync and add its informer, lister, and config observer
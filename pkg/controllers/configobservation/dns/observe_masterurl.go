@@ -0,0 +1,70 @@
+package dns
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/operator/configobserver"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
+)
+
+// defaultClusterDomain is the cluster domain the oauth-server is reachable at
+// via its default ".svc" service URL
+const defaultClusterDomain = "cluster.local"
+
+var masterURLPath = []string{"oauthConfig", "masterURL"}
+
+// ObserveMasterURL renders the internal URL of the oauth-server service within
+// the cluster domain of the cluster DNS. The default cluster domain is left to
+// the ".svc" service URL the oauth-server is configured with by default.
+func ObserveMasterURL(genericlisters configobserver.Listers, recorder events.Recorder, existingConfig map[string]interface{}) (ret map[string]interface{}, _ []error) {
+	defer func() {
+		ret = configobserver.Pruned(ret, masterURLPath)
+	}()
+	listers := genericlisters.(configobservation.Listers)
+	errs := []error{}
+
+	dnsConfig, err := listers.DNSLister.Get("default")
+	if errors.IsNotFound(err) {
+		klog.Warning("dnses.operator.openshift.io/default: not found")
+		return existingConfig, errs
+	} else if err != nil {
+		return existingConfig, append(errs, err)
+	}
+
+	// the cluster domain is not reported yet
+	clusterDomain := dnsConfig.Status.ClusterDomain
+	if len(clusterDomain) == 0 {
+		return existingConfig, errs
+	}
+	if msgs := validation.IsDNS1123Subdomain(clusterDomain); len(msgs) > 0 {
+		return existingConfig, append(errs, fmt.Errorf("invalid cluster domain %q: %v", clusterDomain, msgs))
+	}
+
+	observedConfig := map[string]interface{}{}
+	var observedMasterURL string
+	if clusterDomain != defaultClusterDomain {
+		observedMasterURL = fmt.Sprintf("https://oauth-openshift.openshift-authentication.svc.%s", clusterDomain)
+		if err := unstructured.SetNestedField(observedConfig, observedMasterURL, masterURLPath...); err != nil {
+			return existingConfig, append(errs, err)
+		}
+	}
+
+	currentMasterURL, _, err := unstructured.NestedString(existingConfig, masterURLPath...)
+	if err != nil {
+		// continue on read error from existing config in an attempt to fix it
+		errs = append(errs, err)
+	}
+
+	if currentMasterURL != observedMasterURL {
+		recorder.Eventf("ObserveMasterURL", "masterURL changed from %q to %q", currentMasterURL, observedMasterURL)
+	}
+
+	return observedConfig, errs
+}
@@ -0,0 +1,119 @@
+package dns
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	operatorlistersv1 "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
+)
+
+func TestObserveMasterURL(t *testing.T) {
+	existingConfig := configWithMasterURL("https://oauth-openshift.openshift-authentication.svc.my.domain")
+
+	tests := []struct {
+		name                string
+		dnsStatus           *operatorv1.DNSStatus
+		existingConfig      map[string]interface{}
+		expectedConfig      map[string]interface{}
+		expectedErrs        []string
+		expectedUpdateEvent bool
+	}{
+		{
+			name:           "NoDNSConfig",
+			existingConfig: existingConfig,
+			expectedConfig: existingConfig,
+		},
+		{
+			name:           "NoClusterDomain",
+			dnsStatus:      &operatorv1.DNSStatus{},
+			existingConfig: existingConfig,
+			expectedConfig: existingConfig,
+		},
+		{
+			name:           "DefaultClusterDomain",
+			dnsStatus:      &operatorv1.DNSStatus{ClusterDomain: "cluster.local"},
+			existingConfig: map[string]interface{}{},
+			expectedConfig: map[string]interface{}{},
+		},
+		{
+			name:                "ChangedToDefaultClusterDomain",
+			dnsStatus:           &operatorv1.DNSStatus{ClusterDomain: "cluster.local"},
+			existingConfig:      existingConfig,
+			expectedConfig:      map[string]interface{}{},
+			expectedUpdateEvent: true,
+		},
+		{
+			name:           "SameClusterDomain",
+			dnsStatus:      &operatorv1.DNSStatus{ClusterDomain: "my.domain"},
+			existingConfig: existingConfig,
+			expectedConfig: existingConfig,
+		},
+		{
+			name:                "CustomClusterDomain",
+			dnsStatus:           &operatorv1.DNSStatus{ClusterDomain: "trust.example.com"},
+			existingConfig:      map[string]interface{}{},
+			expectedConfig:      configWithMasterURL("https://oauth-openshift.openshift-authentication.svc.trust.example.com"),
+			expectedUpdateEvent: true,
+		},
+		{
+			name:           "InvalidClusterDomain",
+			dnsStatus:      &operatorv1.DNSStatus{ClusterDomain: "not a domain"},
+			existingConfig: existingConfig,
+			expectedConfig: existingConfig,
+			expectedErrs:   []string{"invalid cluster domain \"not a domain\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if tt.dnsStatus != nil {
+				if err := indexer.Add(&operatorv1.DNS{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "default",
+					},
+					Status: *tt.dnsStatus,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			listers := configobservation.Listers{
+				DNSLister: operatorlistersv1.NewDNSLister(indexer),
+			}
+
+			eventRecorder := events.NewInMemoryRecorder(tt.name)
+			gotConfig, errs := ObserveMasterURL(listers, eventRecorder, tt.existingConfig)
+			if !reflect.DeepEqual(gotConfig, tt.expectedConfig) {
+				t.Errorf("ObserveMasterURL() gotConfig = %v, want %v", gotConfig, tt.expectedConfig)
+			}
+
+			if len(errs) != len(tt.expectedErrs) {
+				t.Fatalf("expected errors %v, got %v", tt.expectedErrs, errs)
+			}
+			for i := range errs {
+				if !strings.Contains(errs[i].Error(), tt.expectedErrs[i]) {
+					t.Errorf("expected error %d to contain %q, got %v", i, tt.expectedErrs[i], errs[i])
+				}
+			}
+
+			if gotEvent := len(eventRecorder.Events()) > 0; gotEvent != tt.expectedUpdateEvent {
+				t.Errorf("expected update event: %t, got events %v", tt.expectedUpdateEvent, eventRecorder.Events())
+			}
+		})
+	}
+}
+
+func configWithMasterURL(masterURL string) map[string]interface{} {
+	return map[string]interface{}{
+		"oauthConfig": map[string]interface{}{
+			"masterURL": masterURL,
+		},
+	}
+}
@@ -1,12 +1,16 @@
 package configobservation
 
 import (
+	"k8s.io/apimachinery/pkg/api/errors"
 	corelistersv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 
 	configlistersv1 "github.com/openshift/client-go/config/listers/config/v1"
+	operatorlistersv1 "github.com/openshift/client-go/operator/listers/operator/v1"
 	"github.com/openshift/library-go/pkg/operator/configobserver"
 	"github.com/openshift/library-go/pkg/operator/resourcesynccontroller"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
 )
 
 var _ configobserver.Listers = Listers{}
@@ -26,6 +30,9 @@ type Listers struct {
 	OAuthLister_         configlistersv1.OAuthLister
 	IngressLister        configlistersv1.IngressLister
 
+	AuthenticationOperatorLister operatorlistersv1.AuthenticationLister
+	DNSLister                    operatorlistersv1.DNSLister
+
 	ResourceSync       resourcesynccontroller.ResourceSyncer
 	PreRunCachesSynced []cache.InformerSynced
 }
@@ -45,3 +52,21 @@ func (l Listers) OAuthLister() configlistersv1.OAuthLister {
 func (l Listers) PreRunHasSynced() []cache.InformerSynced {
 	return l.PreRunCachesSynced
 }
+
+// UnsupportedConfigOverrides returns the decoded unsupportedConfigOverrides of the
+// authentication operator config. An empty map is returned when the operator
+// config is not available.
+func (l Listers) UnsupportedConfigOverrides() (map[string]interface{}, error) {
+	if l.AuthenticationOperatorLister == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	operatorConfig, err := l.AuthenticationOperatorLister.Get("cluster")
+	if errors.IsNotFound(err) {
+		return map[string]interface{}{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return common.UnsupportedConfigOverrides(&operatorConfig.Spec.OperatorSpec)
+}
@@ -0,0 +1,154 @@
+package oauth
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	corelistersv1 "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// AuditPolicyConfigMapName is the name of the configmap in the
+	// openshift-authentication namespace that is mounted as the audit policy.
+	AuditPolicyConfigMapName = "audit"
+	// AuditPolicyKey is the key of the audit policy within the audit configmap
+	AuditPolicyKey = "audit.yaml"
+)
+
+var (
+	auditCustomPolicyConfigMapPath = []string{
+		"audit", "customPolicyConfigMap",
+	}
+	// ObservedAuditPolicyConfigMapPath is where the validated custom policy
+	// configmap is recorded in the observed config of the oauth-server
+	ObservedAuditPolicyConfigMapPath = []string{
+		"auditPolicy", "configMap",
+	}
+	// ObservedAuditProfilePath is where the audit profile of the cluster is
+	// recorded in the observed config of the oauth-server when the audit policy
+	// is generated from it rather than being the operator's default policy
+	ObservedAuditProfilePath = []string{
+		"auditPolicy", "profile",
+	}
+	// ObservedAuditCustomRulesPath is where the per-group audit profiles of the
+	// cluster are recorded along with ObservedAuditProfilePath
+	ObservedAuditCustomRulesPath = []string{
+		"auditPolicy", "customRules",
+	}
+	// ObservedAuditAuthorizationLevelPath is where the audit level that
+	// authorization decisions are always captured at is recorded
+	ObservedAuditAuthorizationLevelPath = []string{
+		"auditPolicy", "authorizationDecisionsLevel",
+	}
+	auditAuthorizationLevelPath = []string{
+		"audit", "authorizationDecisionsLevel",
+	}
+	// ObservedAuditIncludeRequestReceivedPath is where it is recorded that
+	// audit events are generated in the RequestReceived stage, too
+	ObservedAuditIncludeRequestReceivedPath = []string{
+		"auditPolicy", "includeRequestReceivedStage",
+	}
+	auditIncludeRequestReceivedPath = []string{
+		"audit", "includeRequestReceivedStage",
+	}
+	// authorizationAuditLevels are the audit levels authorization decisions can
+	// be captured at
+	authorizationAuditLevels = sets.NewString(
+		string(auditv1.LevelMetadata),
+		string(auditv1.LevelRequest),
+		string(auditv1.LevelRequestResponse),
+	)
+
+	auditScheme = runtime.NewScheme()
+	auditCodecs = serializer.NewCodecFactory(auditScheme, serializer.EnableStrict)
+)
+
+func init() {
+	utilruntime.Must(auditv1.AddToScheme(auditScheme))
+}
+
+// CustomAuditPolicyConfigMap returns the name of the configmap in the
+// openshift-config namespace that provides the audit policy instead of the
+// operator's default one, as set in unsupportedConfigOverrides.operator
+// (audit.customPolicyConfigMap). An empty name means the default policy is used.
+func CustomAuditPolicyConfigMap(unsupportedConfig map[string]interface{}) (string, error) {
+	name, _, err := unstructured.NestedString(unsupportedConfig, auditCustomPolicyConfigMapPath...)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", strings.Join(auditCustomPolicyConfigMapPath, "."), err)
+	}
+	return name, nil
+}
+
+// AuthorizationAuditRule returns an audit policy rule that captures the
+// authorization decisions of the oauth-server at the given level. These are
+// made by its own endpoints rather than by API resources: the authorize
+// endpoint grants or denies access to a client, including the approval of the
+// requested scopes, and the token endpoint exchanges a grant for a token.
+func AuthorizationAuditRule(level auditv1.Level) auditv1.PolicyRule {
+	return auditv1.PolicyRule{
+		Level: level,
+		NonResourceURLs: []string{
+			"/oauth/authorize",
+			"/oauth/authorize/*",
+			"/oauth/token",
+		},
+	}
+}
+
+// authorizationAuditLevel returns the audit level that authorization decisions
+// are always captured at regardless of the audit profile, as set in
+// unsupportedConfigOverrides.operator (audit.authorizationDecisionsLevel). An empty
+// level means authorization decisions are audited as per the profile.
+func authorizationAuditLevel(unsupportedConfig map[string]interface{}) (string, error) {
+	fieldName := strings.Join(auditAuthorizationLevelPath, ".")
+
+	level, _, err := unstructured.NestedString(unsupportedConfig, auditAuthorizationLevelPath...)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", fieldName, err)
+	}
+	if len(level) > 0 && !authorizationAuditLevels.Has(level) {
+		return "", fmt.Errorf("%s must be one of %v, got %q", fieldName, authorizationAuditLevels.List(), level)
+	}
+	return level, nil
+}
+
+// validateCustomAuditPolicy checks that the configmap exists in openshift-config
+// and that it carries a parsable audit policy under the expected key.
+func validateCustomAuditPolicy(cmLister corelistersv1.ConfigMapLister, name string) error {
+	cm, err := cmLister.ConfigMaps("openshift-config").Get(name)
+	if err != nil {
+		return fmt.Errorf("failed to get the custom audit policy configmap openshift-config/%s: %w", name, err)
+	}
+
+	data, ok := cm.Data[AuditPolicyKey]
+	if !ok {
+		return fmt.Errorf("the custom audit policy configmap openshift-config/%s is missing the %q key", name, AuditPolicyKey)
+	}
+
+	if _, err := ParseAuditPolicy([]byte(data)); err != nil {
+		return fmt.Errorf("the custom audit policy configmap openshift-config/%s does not contain a valid policy: %w", name, err)
+	}
+
+	return nil
+}
+
+// ParseAuditPolicy strictly decodes an audit.k8s.io/v1 Policy
+func ParseAuditPolicy(data []byte) (*auditv1.Policy, error) {
+	obj, _, err := auditCodecs.UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, ok := obj.(*auditv1.Policy)
+	if !ok {
+		return nil, fmt.Errorf("expected an audit.k8s.io/v1 Policy, got %T", obj)
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,114 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	osinv1 "github.com/openshift/api/osin/v1"
+)
+
+var allowInsecureIdentityProviderURLsPath = []string{"identityProviders", "allowInsecureURLs"}
+
+// validateIdentityProviderTransport returns an error if any of the observed
+// identity providers would make the oauth-server talk to it, or send the user
+// to it, over a connection that is not protected by TLS. Insecure identity
+// providers can be allowed via identityProviders.allowInsecureURLs in
+// unsupportedConfigOverrides.operator, e.g. for development clusters.
+func validateIdentityProviderTransport(observedConfig, unsupportedConfig map[string]interface{}) error {
+	allowInsecureURLs, _, err := unstructured.NestedBool(unsupportedConfig, allowInsecureIdentityProviderURLsPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(allowInsecureIdentityProviderURLsPath, "."), err)
+	}
+	if allowInsecureURLs {
+		return nil
+	}
+
+	identityProviders, err := observedIdentityProviders(observedConfig)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, idp := range identityProviders {
+		provider, _, err := codecs.UniversalDecoder(osinv1.GroupVersion).Decode(idp.Provider.Raw, nil, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("identity provider %q: unable to decode the provider: %w", idp.Name, err))
+			continue
+		}
+
+		urls := identityProviderURLs(provider)
+		fields := make([]string, 0, len(urls))
+		for field := range urls {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			rawurl := urls[field]
+			if len(rawurl) == 0 {
+				continue
+			}
+			if u, err := url.Parse(rawurl); err == nil && u.Scheme == "https" {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("identity provider %q: %s %q does not use https", idp.Name, field, rawurl))
+		}
+
+		if ldapProvider, ok := provider.(*osinv1.LDAPPasswordIdentityProvider); ok && ldapProvider.Insecure {
+			errs = append(errs, fmt.Errorf("identity provider %q: insecure is set, the connection to %q is not protected by TLS", idp.Name, ldapProvider.URL))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w (set %s in unsupportedConfigOverrides to allow them)", utilerrors.NewAggregate(errs), strings.Join(allowInsecureIdentityProviderURLsPath, "."))
+	}
+	return nil
+}
+
+// observedIdentityProviders returns the identity providers of the observed oauth-server config
+func observedIdentityProviders(observedConfig map[string]interface{}) ([]osinv1.IdentityProvider, error) {
+	identityProvidersUnstructured, _, err := unstructured.NestedFieldCopy(observedConfig, "oauthConfig", "identityProviders")
+	if err != nil || identityProvidersUnstructured == nil {
+		return nil, err
+	}
+
+	identityProvidersBytes, err := json.Marshal(identityProvidersUnstructured)
+	if err != nil {
+		return nil, err
+	}
+
+	identityProviders := []osinv1.IdentityProvider{}
+	if err := json.Unmarshal(identityProvidersBytes, &identityProviders); err != nil {
+		return nil, fmt.Errorf("unable to decode the observed identity providers: %w", err)
+	}
+	return identityProviders, nil
+}
+
+// identityProviderURLs returns the http(s) URLs of the provider keyed by their field path
+func identityProviderURLs(provider interface{}) map[string]string {
+	switch p := provider.(type) {
+	case *osinv1.BasicAuthPasswordIdentityProvider:
+		return map[string]string{"url": p.URL}
+	case *osinv1.GitLabIdentityProvider:
+		return map[string]string{"url": p.URL}
+	case *osinv1.KeystonePasswordIdentityProvider:
+		return map[string]string{"url": p.URL}
+	case *osinv1.OpenIDIdentityProvider:
+		return map[string]string{
+			"urls.authorize": p.URLs.Authorize,
+			"urls.token":     p.URLs.Token,
+			"urls.userInfo":  p.URLs.UserInfo,
+		}
+	case *osinv1.RequestHeaderIdentityProvider:
+		return map[string]string{
+			"loginURL":     p.LoginURL,
+			"challengeURL": p.ChallengeURL,
+		}
+	}
+	return nil
+}
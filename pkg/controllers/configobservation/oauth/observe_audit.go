@@ -2,6 +2,8 @@ package oauth
 
 import (
 	"fmt"
+	"path"
+	"strconv"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -10,24 +12,185 @@ import (
 	"k8s.io/klog/v2"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/library-go/pkg/operator/apiserver/audit"
 	"github.com/openshift/library-go/pkg/operator/configobserver"
 	"github.com/openshift/library-go/pkg/operator/events"
 
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
 )
 
+const (
+	// DefaultAuditPolicyMountPath is where the audit configmap is mounted to
+	// the oauth-server container unless configured otherwise
+	DefaultAuditPolicyMountPath = "/var/run/configmaps/audit"
+)
+
 var (
 	serverArgumentsPath = []string{
 		"serverArguments",
 	}
-	auditOptionsArgs = map[string]interface{}{
+	auditManageConfigMapPath = []string{
+		"audit", "manageConfigMap",
+	}
+	auditPolicyFilePath = []string{
+		"audit", "policyFile",
+	}
+	auditPolicyMountPathPath = []string{
+		"audit", "policyMountPath",
+	}
+	auditMinimumProfilePath = []string{
+		"audit", "minimumProfile",
+	}
+	auditFallbackProfilePath = []string{
+		"audit", "fallbackProfile",
+	}
+	auditWebhookConfigFilePath = []string{
+		"audit", "webhook", "configFile",
+	}
+	auditWebhookThrottleQPSPath = []string{
+		"audit", "webhook", "throttleQPS",
+	}
+	auditWebhookThrottleBurstPath = []string{
+		"audit", "webhook", "throttleBurst",
+	}
+
+	// auditProfileLevels orders the audit profiles by the audit coverage they provide
+	auditProfileLevels = map[configv1.AuditProfileType]int{
+		configv1.NoneAuditProfileType:               0,
+		configv1.DefaultAuditProfileType:            1,
+		configv1.WriteRequestBodiesAuditProfileType: 2,
+		configv1.AllRequestBodiesAuditProfileType:   3,
+	}
+)
+
+func auditOptionsArgs(policyFile string) map[string]interface{} {
+	return map[string]interface{}{
 		"audit-log-path":      []interface{}{"/var/log/oauth-server/audit.log"},
 		"audit-log-format":    []interface{}{"json"},
 		"audit-log-maxsize":   []interface{}{"100"},
 		"audit-log-maxbackup": []interface{}{"10"},
-		"audit-policy-file":   []interface{}{"/var/run/configmaps/audit/audit.yaml"},
+		"audit-policy-file":   []interface{}{policyFile},
 	}
-)
+}
+
+// AuditConfigMapManaged returns whether the operator owns the audit policy
+// configmap. It can be turned off via unsupportedConfigOverrides.operator
+// (audit.manageConfigMap) for setups where the policy is provided externally.
+func AuditConfigMapManaged(unsupportedConfig map[string]interface{}) (bool, error) {
+	managed, found, err := unstructured.NestedBool(unsupportedConfig, auditManageConfigMapPath...)
+	if err != nil {
+		return true, fmt.Errorf("unable to read %s: %w", strings.Join(auditManageConfigMapPath, "."), err)
+	}
+	if !found {
+		return true, nil
+	}
+	return managed, nil
+}
+
+// checkMinimumAuditProfile returns an error when the given audit profile provides
+// less coverage than the minimum profile set in unsupportedConfigOverrides.operator
+// (audit.minimumProfile). This guards against accidental audit downgrades.
+func checkMinimumAuditProfile(unsupportedConfig map[string]interface{}, profile configv1.AuditProfileType) error {
+	minimumProfile, found, err := unstructured.NestedString(unsupportedConfig, auditMinimumProfilePath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(auditMinimumProfilePath, "."), err)
+	}
+	if !found || len(minimumProfile) == 0 {
+		return nil
+	}
+
+	minimumLevel, ok := auditProfileLevels[configv1.AuditProfileType(minimumProfile)]
+	if !ok {
+		return fmt.Errorf("%s: unknown audit profile %q", strings.Join(auditMinimumProfilePath, "."), minimumProfile)
+	}
+
+	// an unset profile means the Default one
+	if len(profile) == 0 {
+		profile = configv1.DefaultAuditProfileType
+	}
+	level, ok := auditProfileLevels[profile]
+	if !ok {
+		return fmt.Errorf("unknown audit profile %q", profile)
+	}
+
+	if level < minimumLevel {
+		return fmt.Errorf(
+			"audit profile %q is below the minimum audit profile %q, keeping the current audit configuration; remove %s to allow the change",
+			profile,
+			minimumProfile,
+			strings.Join(auditMinimumProfilePath, "."),
+		)
+	}
+
+	return nil
+}
+
+// fallbackAuditProfile returns the audit profile to use while the APIServer
+// config is not available. It is Default unless set in
+// unsupportedConfigOverrides.operator (audit.fallbackProfile).
+func fallbackAuditProfile(unsupportedConfig map[string]interface{}) (configv1.AuditProfileType, error) {
+	fallbackProfile, found, err := unstructured.NestedString(unsupportedConfig, auditFallbackProfilePath...)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", strings.Join(auditFallbackProfilePath, "."), err)
+	}
+	if !found || len(fallbackProfile) == 0 {
+		return configv1.DefaultAuditProfileType, nil
+	}
+
+	if _, ok := auditProfileLevels[configv1.AuditProfileType(fallbackProfile)]; !ok {
+		return "", fmt.Errorf("%s: unknown audit profile %q", strings.Join(auditFallbackProfilePath, "."), fallbackProfile)
+	}
+	return configv1.AuditProfileType(fallbackProfile), nil
+}
+
+// AuditPolicyMountPath returns the directory the audit configmap is mounted
+// to in the oauth-server container. It can be set via unsupportedConfigOverrides.operator
+// (audit.policyMountPath) and determines the default audit-policy-file, so that
+// the server argument and the mount are always in sync.
+func AuditPolicyMountPath(unsupportedConfig map[string]interface{}) (string, error) {
+	mountPath, found, err := unstructured.NestedString(unsupportedConfig, auditPolicyMountPathPath...)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", strings.Join(auditPolicyMountPathPath, "."), err)
+	}
+	if !found || len(mountPath) == 0 {
+		return DefaultAuditPolicyMountPath, nil
+	}
+	if !path.IsAbs(mountPath) || path.Clean(mountPath) == "/" {
+		return "", fmt.Errorf("%s must be an absolute path below /, got %q", strings.Join(auditPolicyMountPathPath, "."), mountPath)
+	}
+	return path.Clean(mountPath), nil
+}
+
+// auditPolicyFile returns the path of the audit policy passed to the
+// oauth-server. An externally-provided path is only honored when the audit
+// configmap is not managed by the operator.
+func auditPolicyFile(unsupportedConfig map[string]interface{}) (string, error) {
+	mountPath, err := AuditPolicyMountPath(unsupportedConfig)
+	if err != nil {
+		return "", err
+	}
+	defaultAuditPolicyFile := path.Join(mountPath, AuditPolicyKey)
+
+	managed, err := AuditConfigMapManaged(unsupportedConfig)
+	if err != nil {
+		return "", err
+	}
+	if managed {
+		return defaultAuditPolicyFile, nil
+	}
+
+	policyFile, found, err := unstructured.NestedString(unsupportedConfig, auditPolicyFilePath...)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", strings.Join(auditPolicyFilePath, "."), err)
+	}
+	if !found || len(policyFile) == 0 {
+		return defaultAuditPolicyFile, nil
+	}
+	if !path.IsAbs(policyFile) {
+		return "", fmt.Errorf("%s must be an absolute path, got %q", strings.Join(auditPolicyFilePath, "."), policyFile)
+	}
+	return path.Clean(policyFile), nil
+}
 
 func ObserveAudit(
 	genericListers configobserver.Listers,
@@ -35,7 +198,7 @@ func ObserveAudit(
 	existingConfig map[string]interface{},
 ) (ret map[string]interface{}, _ []error) {
 	defer func() {
-		ret = configobserver.Pruned(ret, serverArgumentsPath)
+		ret = configobserver.Pruned(ret, serverArgumentsPath, ObservedAuditPolicyConfigMapPath, ObservedAuditProfilePath, ObservedAuditCustomRulesPath, ObservedAuditAuthorizationLevelPath, ObservedAuditIncludeRequestReceivedPath)
 	}()
 
 	listers := genericListers.(configobservation.Listers)
@@ -51,16 +214,47 @@ func ObserveAudit(
 		))
 	}
 
-	var observedAuditProfile configv1.AuditProfileType
+	var observedAudit configv1.Audit
 	if apiServer != nil {
-		observedAuditProfile = apiServer.Spec.Audit.Profile
+		observedAudit = apiServer.Spec.Audit
+	}
+	observedAuditProfile := observedAudit.Profile
+
+	unsupportedConfig, err := listers.UnsupportedConfigOverrides()
+	if err != nil {
+		return existingConfig, append(errs, fmt.Errorf(
+			"failed to get unsupportedConfigOverrides of operator.openshift.io/cluster: %w",
+			err,
+		))
+	}
+
+	if apiServer == nil {
+		fallbackProfile, err := fallbackAuditProfile(unsupportedConfig)
+		if err != nil {
+			return existingConfig, append(errs, err)
+		}
+		observedAudit = configv1.Audit{Profile: fallbackProfile}
+		observedAuditProfile = fallbackProfile
+	}
+
+	if err := checkMinimumAuditProfile(unsupportedConfig, observedAuditProfile); err != nil {
+		return existingConfig, append(errs, err)
+	}
+
+	policyFile, err := auditPolicyFile(unsupportedConfig)
+	if err != nil {
+		return existingConfig, append(errs, err)
+	}
+	observedAuditOptionsArgs := auditOptionsArgs(policyFile)
+	if err := observeAuditWebhook(observedAuditOptionsArgs, unsupportedConfig); err != nil {
+		return existingConfig, append(errs, err)
 	}
 
 	observedConfig := map[string]interface{}{}
 	if observedAuditProfile != configv1.NoneAuditProfileType {
 		if err := unstructured.SetNestedField(
 			observedConfig,
-			auditOptionsArgs,
+			observedAuditOptionsArgs,
 			serverArgumentsPath...,
 		); err != nil {
 			return existingConfig, append(errs, fmt.Errorf(
@@ -72,6 +266,61 @@ func ObserveAudit(
 		}
 	}
 
+	customPolicyConfigMap, err := observeCustomAuditPolicy(listers, unsupportedConfig, observedAuditProfile)
+	if err != nil {
+		return existingConfig, append(errs, err)
+	}
+
+	existingCustomPolicyConfigMap, _, err := unstructured.NestedString(existingConfig, ObservedAuditPolicyConfigMapPath...)
+	if err != nil {
+		return existingConfig, append(errs, err)
+	}
+
+	if len(customPolicyConfigMap) > 0 {
+		if err := unstructured.SetNestedField(observedConfig, customPolicyConfigMap, ObservedAuditPolicyConfigMapPath...); err != nil {
+			return existingConfig, append(errs, err)
+		}
+	}
+
+	if existingCustomPolicyConfigMap != customPolicyConfigMap {
+		recorder.Eventf(
+			"ObserveAuditPolicy",
+			"custom audit policy configmap changed from %q to %q",
+			existingCustomPolicyConfigMap,
+			customPolicyConfigMap,
+		)
+	}
+
+	if len(customPolicyConfigMap) == 0 {
+		if err := observeAuditProfilePolicy(observedConfig, unsupportedConfig, observedAudit); err != nil {
+			return existingConfig, append(errs, err)
+		}
+		if err := observeAuthorizationAuditLevel(observedConfig, unsupportedConfig, observedAuditProfile); err != nil {
+			return existingConfig, append(errs, err)
+		}
+		if err := observeRequestReceivedStage(observedConfig, unsupportedConfig, observedAuditProfile); err != nil {
+			return existingConfig, append(errs, err)
+		}
+	}
+
+	existingPolicyProfile, _, err := unstructured.NestedString(existingConfig, ObservedAuditProfilePath...)
+	if err != nil {
+		return existingConfig, append(errs, err)
+	}
+	observedPolicyProfile, _, err := unstructured.NestedString(observedConfig, ObservedAuditProfilePath...)
+	if err != nil {
+		return existingConfig, append(errs, err)
+	}
+
+	if existingPolicyProfile != observedPolicyProfile {
+		recorder.Eventf(
+			"ObserveAuditPolicyProfile",
+			"audit policy profile changed from %q to %q",
+			existingPolicyProfile,
+			observedPolicyProfile,
+		)
+	}
+
 	currentAuditProfile, _, err := unstructured.NestedFieldCopy(
 		existingConfig,
 		serverArgumentsPath...,
@@ -80,14 +329,161 @@ func ObserveAudit(
 		return existingConfig, append(errs, err)
 	}
 
-	if !equality.Semantic.DeepEqual(currentAuditProfile, auditOptionsArgs) {
+	if !equality.Semantic.DeepEqual(currentAuditProfile, observedAuditOptionsArgs) {
 		recorder.Eventf(
 			"ObserveAuditProfile",
 			"AuditProfile changed from '%s' to '%s'",
 			currentAuditProfile,
-			auditOptionsArgs,
+			observedAuditOptionsArgs,
 		)
 	}
 
 	return observedConfig, errs
 }
+
+// observeAuditWebhook adds the server arguments of the webhook audit backend
+// to args when audit.webhook.configFile is set in unsupportedConfigOverrides.operator.
+// The batches sent to the webhook are throttled by audit.webhook.throttleQPS
+// and audit.webhook.throttleBurst, which are omitted while the webhook backend
+// is off. The throttle values are validated when the deployment is rendered.
+func observeAuditWebhook(args, unsupportedConfig map[string]interface{}) error {
+	configFile, _, err := unstructured.NestedString(unsupportedConfig, auditWebhookConfigFilePath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(auditWebhookConfigFilePath, "."), err)
+	}
+	if len(configFile) == 0 {
+		return nil
+	}
+	if !path.IsAbs(configFile) {
+		return fmt.Errorf("%s must be an absolute path, got %q", strings.Join(auditWebhookConfigFilePath, "."), configFile)
+	}
+	args["audit-webhook-config-file"] = []interface{}{path.Clean(configFile)}
+
+	for flag, fieldPath := range map[string][]string{
+		"audit-webhook-batch-throttle-qps":   auditWebhookThrottleQPSPath,
+		"audit-webhook-batch-throttle-burst": auditWebhookThrottleBurstPath,
+	} {
+		value, found, err := unstructured.NestedFloat64(unsupportedConfig, fieldPath...)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", strings.Join(fieldPath, "."), err)
+		}
+		if found {
+			args[flag] = []interface{}{strconv.FormatFloat(value, 'f', -1, 64)}
+		}
+	}
+
+	return nil
+}
+
+// observeCustomAuditPolicy returns the validated name of the configmap in
+// openshift-config that provides the audit policy. The custom policy is only
+// used while auditing is enabled and the audit configmap is owned by the operator.
+func observeCustomAuditPolicy(
+	listers configobservation.Listers,
+	unsupportedConfig map[string]interface{},
+	profile configv1.AuditProfileType,
+) (string, error) {
+	if profile == configv1.NoneAuditProfileType {
+		return "", nil
+	}
+
+	managed, err := AuditConfigMapManaged(unsupportedConfig)
+	if err != nil || !managed {
+		return "", err
+	}
+
+	name, err := CustomAuditPolicyConfigMap(unsupportedConfig)
+	if err != nil || len(name) == 0 {
+		return "", err
+	}
+
+	if err := validateCustomAuditPolicy(listers.ConfigMapLister, name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// observeAuditProfilePolicy records the audit profile and the per-group custom
+// rules of the cluster in the observed config when the audit policy has to be
+// generated from them. The operator's default audit policy is kept for the
+// Default profile without custom rules, and the policy is not used at all when
+// auditing is off or when the audit configmap is not managed by the operator.
+func observeAuditProfilePolicy(observedConfig, unsupportedConfig map[string]interface{}, observedAudit configv1.Audit) error {
+	profile := observedAudit.Profile
+	if len(profile) == 0 {
+		profile = configv1.DefaultAuditProfileType
+	}
+	if profile == configv1.NoneAuditProfileType {
+		return nil
+	}
+	if profile == configv1.DefaultAuditProfileType && len(observedAudit.CustomRules) == 0 {
+		return nil
+	}
+
+	managed, err := AuditConfigMapManaged(unsupportedConfig)
+	if err != nil || !managed {
+		return err
+	}
+
+	// the policy is generated later on, make sure it can be
+	if _, err := audit.GetAuditPolicy(configv1.Audit{Profile: profile, CustomRules: observedAudit.CustomRules}); err != nil {
+		return fmt.Errorf("invalid audit configuration: %w", err)
+	}
+
+	if err := unstructured.SetNestedField(observedConfig, string(profile), ObservedAuditProfilePath...); err != nil {
+		return err
+	}
+
+	if len(observedAudit.CustomRules) == 0 {
+		return nil
+	}
+	customRules := make([]interface{}, 0, len(observedAudit.CustomRules))
+	for _, rule := range observedAudit.CustomRules {
+		customRules = append(customRules, map[string]interface{}{
+			"group":   rule.Group,
+			"profile": string(rule.Profile),
+		})
+	}
+	return unstructured.SetNestedSlice(observedConfig, customRules, ObservedAuditCustomRulesPath...)
+}
+
+// observeAuthorizationAuditLevel records the audit level that authorization
+// decisions are always captured at in the observed config. It only applies to
+// the audit policy managed by the operator while auditing is enabled.
+func observeAuthorizationAuditLevel(observedConfig, unsupportedConfig map[string]interface{}, profile configv1.AuditProfileType) error {
+	level, err := authorizationAuditLevel(unsupportedConfig)
+	if err != nil || len(level) == 0 || profile == configv1.NoneAuditProfileType {
+		return err
+	}
+
+	managed, err := AuditConfigMapManaged(unsupportedConfig)
+	if err != nil || !managed {
+		return err
+	}
+
+	return unstructured.SetNestedField(observedConfig, level, ObservedAuditAuthorizationLevelPath...)
+}
+
+// observeRequestReceivedStage records in the observed config that audit events
+// are generated in the RequestReceived stage, too, when
+// audit.includeRequestReceivedStage is set in unsupportedConfigOverrides.operator. The
+// stage is omitted by default as it doubles the audit volume without adding
+// much information. It only applies to the audit policy managed by the
+// operator while auditing is enabled.
+func observeRequestReceivedStage(observedConfig, unsupportedConfig map[string]interface{}, profile configv1.AuditProfileType) error {
+	include, _, err := unstructured.NestedBool(unsupportedConfig, auditIncludeRequestReceivedPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(auditIncludeRequestReceivedPath, "."), err)
+	}
+	if !include || profile == configv1.NoneAuditProfileType {
+		return nil
+	}
+
+	managed, err := AuditConfigMapManaged(unsupportedConfig)
+	if err != nil || !managed {
+		return err
+	}
+
+	return unstructured.SetNestedField(observedConfig, true, ObservedAuditIncludeRequestReceivedPath...)
+}
@@ -5,14 +5,20 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corelistersv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 
 	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
 	configlistersv1 "github.com/openshift/client-go/config/listers/config/v1"
+	operatorlistersv1 "github.com/openshift/client-go/operator/listers/operator/v1"
 	"github.com/openshift/library-go/pkg/operator/events"
 
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/oauth"
 )
@@ -66,7 +72,7 @@ func TestAuditProfile(t *testing.T) {
 				},
 			},
 			previouslyObservedConfig: map[string]interface{}{},
-			expected:                 auditOpts,
+			expected:                 withAuditPolicy(auditOpts, map[string]interface{}{"profile": "WriteRequestBodies"}),
 		},
 		{
 			name: "turn off, from being turned on",
@@ -92,7 +98,7 @@ func TestAuditProfile(t *testing.T) {
 				},
 			},
 			previouslyObservedConfig: map[string]interface{}{},
-			expected:                 auditOpts,
+			expected:                 withAuditPolicy(auditOpts, map[string]interface{}{"profile": "AllRequestBodies"}),
 		},
 		{
 			name: "turn on, with Default",
@@ -132,3 +138,824 @@ func TestAuditProfile(t *testing.T) {
 		})
 	}
 }
+
+func TestAuditConfigMapManagement(t *testing.T) {
+	auditOptsWithPolicyFile := func(policyFile string) map[string]interface{} {
+		return map[string]interface{}{
+			"serverArguments": map[string]interface{}{
+				"audit-log-format":    []interface{}{string("json")},
+				"audit-log-maxbackup": []interface{}{string("10")},
+				"audit-log-maxsize":   []interface{}{string("100")},
+				"audit-log-path":      []interface{}{string("/var/log/oauth-server/audit.log")},
+				"audit-policy-file":   []interface{}{policyFile},
+			},
+		}
+	}
+
+	for _, tt := range [...]struct {
+		name              string
+		unsupportedConfig string
+		expectedManaged   bool
+		expected          map[string]interface{}
+		expectErr         bool
+	}{
+		{
+			name:            "managed by default",
+			expectedManaged: true,
+			expected:        auditOptsWithPolicyFile("/var/run/configmaps/audit/audit.yaml"),
+		},
+		{
+			name:              "managed, external policy file is ignored",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":true,"policyFile":"/etc/audit/policy.yaml"}}}`,
+			expectedManaged:   true,
+			expected:          auditOptsWithPolicyFile("/var/run/configmaps/audit/audit.yaml"),
+		},
+		{
+			name:              "unmanaged, default policy file",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false}}}`,
+			expectedManaged:   false,
+			expected:          auditOptsWithPolicyFile("/var/run/configmaps/audit/audit.yaml"),
+		},
+		{
+			name:              "unmanaged, external policy file",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false,"policyFile":"/var/run/configmaps/audit/external.yaml"}}}`,
+			expectedManaged:   false,
+			expected:          auditOptsWithPolicyFile("/var/run/configmaps/audit/external.yaml"),
+		},
+		{
+			name:              "managed, custom mount path",
+			unsupportedConfig: `{"operator":{"audit":{"policyMountPath":"/etc/oauth-server/audit/"}}}`,
+			expectedManaged:   true,
+			expected:          auditOptsWithPolicyFile("/etc/oauth-server/audit/audit.yaml"),
+		},
+		{
+			name:              "unmanaged, custom mount path",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false,"policyMountPath":"/etc/oauth-server/audit"}}}`,
+			expectedManaged:   false,
+			expected:          auditOptsWithPolicyFile("/etc/oauth-server/audit/audit.yaml"),
+		},
+		{
+			name:              "relative mount path",
+			unsupportedConfig: `{"operator":{"audit":{"policyMountPath":"audit"}}}`,
+			expectedManaged:   true,
+			expected:          map[string]interface{}{},
+			expectErr:         true,
+		},
+		{
+			name:              "root mount path",
+			unsupportedConfig: `{"operator":{"audit":{"policyMountPath":"/"}}}`,
+			expectedManaged:   true,
+			expected:          map[string]interface{}{},
+			expectErr:         true,
+		},
+		{
+			name:              "unmanaged, relative policy file",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false,"policyFile":"audit.yaml"}}}`,
+			expectedManaged:   false,
+			expected:          map[string]interface{}{},
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			operatorConfig := &operatorv1.Authentication{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			}
+			if len(tt.unsupportedConfig) > 0 {
+				operatorConfig.Spec.UnsupportedConfigOverrides.Raw = []byte(tt.unsupportedConfig)
+			}
+
+			operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := operatorIndexer.Add(operatorConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			unsupportedConfig, err := common.UnsupportedConfigOverrides(&operatorConfig.Spec.OperatorSpec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			managed, err := oauth.AuditConfigMapManaged(unsupportedConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if managed != tt.expectedManaged {
+				t.Errorf("expected managed to be %t, got %t", tt.expectedManaged, managed)
+			}
+
+			listers := configobservation.Listers{
+				APIServerLister_:             configlistersv1.NewAPIServerLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(operatorIndexer),
+			}
+
+			have, errs := oauth.ObserveAudit(listers, events.NewInMemoryRecorder(t.Name()), map[string]interface{}{})
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, have) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, have))
+			}
+		})
+	}
+}
+
+func TestObserveCustomAuditPolicy(t *testing.T) {
+	const validPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+- level: Metadata
+`
+	const updatedPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+- level: RequestResponse
+`
+
+	auditOpts := map[string]interface{}{
+		"audit-log-format":    []interface{}{string("json")},
+		"audit-log-maxbackup": []interface{}{string("10")},
+		"audit-log-maxsize":   []interface{}{string("100")},
+		"audit-log-path":      []interface{}{string("/var/log/oauth-server/audit.log")},
+		"audit-policy-file":   []interface{}{string("/var/run/configmaps/audit/audit.yaml")},
+	}
+	withCustomPolicy := map[string]interface{}{
+		"serverArguments": auditOpts,
+		"auditPolicy": map[string]interface{}{
+			"configMap": "git-synced-policy",
+		},
+	}
+
+	for _, tt := range [...]struct {
+		name                     string
+		policy                   string
+		previouslyObservedConfig map[string]interface{}
+		expected                 map[string]interface{}
+		expectErr                bool
+	}{
+		{
+			name:                     "valid policy",
+			policy:                   validPolicy,
+			previouslyObservedConfig: map[string]interface{}{},
+			expected:                 withCustomPolicy,
+		},
+		{
+			name:                     "valid update",
+			policy:                   updatedPolicy,
+			previouslyObservedConfig: withCustomPolicy,
+			expected:                 withCustomPolicy,
+		},
+		{
+			name:                     "invalid content keeps the previous config",
+			policy:                   "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules: not-a-list\n",
+			previouslyObservedConfig: withCustomPolicy,
+			expected:                 withCustomPolicy,
+			expectErr:                true,
+		},
+		{
+			name:                     "not a policy",
+			policy:                   "apiVersion: v1\nkind: ConfigMap\n",
+			previouslyObservedConfig: map[string]interface{}{},
+			expected:                 map[string]interface{}{},
+			expectErr:                true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "git-synced-policy", Namespace: "openshift-config"},
+				Data:       map[string]string{"audit.yaml": tt.policy},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := operatorIndexer.Add(&operatorv1.Authentication{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Spec: operatorv1.AuthenticationSpec{
+					OperatorSpec: operatorv1.OperatorSpec{
+						UnsupportedConfigOverrides: runtime.RawExtension{
+							Raw: []byte(`{"operator":{"audit":{"customPolicyConfigMap":"git-synced-policy"}}}`),
+						},
+					},
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				APIServerLister_:             configlistersv1.NewAPIServerLister(indexer),
+				ConfigMapLister:              corelistersv1.NewConfigMapLister(indexer),
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(operatorIndexer),
+			}
+
+			have, errs := oauth.ObserveAudit(listers, events.NewInMemoryRecorder(t.Name()), tt.previouslyObservedConfig)
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, have) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, have))
+			}
+		})
+	}
+}
+
+func TestAuditMinimumProfile(t *testing.T) {
+	auditOpts := map[string]interface{}{
+		"serverArguments": map[string]interface{}{
+			"audit-log-format":    []interface{}{string("json")},
+			"audit-log-maxbackup": []interface{}{string("10")},
+			"audit-log-maxsize":   []interface{}{string("100")},
+			"audit-log-path":      []interface{}{string("/var/log/oauth-server/audit.log")},
+			"audit-policy-file":   []interface{}{string("/var/run/configmaps/audit/audit.yaml")},
+		},
+	}
+
+	for _, tt := range [...]struct {
+		name              string
+		profile           configv1.AuditProfileType
+		unsupportedConfig string
+		expected          map[string]interface{}
+		expectErr         bool
+	}{
+		{
+			name:     "no minimum profile allows None",
+			profile:  configv1.NoneAuditProfileType,
+			expected: map[string]interface{}{},
+		},
+		{
+			name:              "allowed transition",
+			profile:           configv1.WriteRequestBodiesAuditProfileType,
+			unsupportedConfig: `{"operator":{"audit":{"minimumProfile":"Default"}}}`,
+			expected:          withAuditPolicy(auditOpts, map[string]interface{}{"profile": "WriteRequestBodies"}),
+		},
+		{
+			name:              "blocked transition",
+			profile:           configv1.NoneAuditProfileType,
+			unsupportedConfig: `{"operator":{"audit":{"minimumProfile":"AllRequestBodies"}}}`,
+			expected:          auditOpts,
+			expectErr:         true,
+		},
+		{
+			name:              "unset profile is Default",
+			unsupportedConfig: `{"operator":{"audit":{"minimumProfile":"WriteRequestBodies"}}}`,
+			expected:          auditOpts,
+			expectErr:         true,
+		},
+		{
+			name:              "unknown minimum profile",
+			profile:           configv1.DefaultAuditProfileType,
+			unsupportedConfig: `{"operator":{"audit":{"minimumProfile":"Everything"}}}`,
+			expected:          auditOpts,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(&configv1.APIServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Spec: configv1.APIServerSpec{
+					Audit: configv1.Audit{Profile: tt.profile},
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			operatorConfig := &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+			if len(tt.unsupportedConfig) > 0 {
+				operatorConfig.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)}
+			}
+			if err := operatorIndexer.Add(operatorConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				APIServerLister_:             configlistersv1.NewAPIServerLister(indexer),
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(operatorIndexer),
+			}
+
+			// the previously observed config has auditing enabled
+			have, errs := oauth.ObserveAudit(listers, events.NewInMemoryRecorder(t.Name()), auditOpts)
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, have) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, have))
+			}
+		})
+	}
+}
+
+func TestAuditProfilePolicy(t *testing.T) {
+	auditOpts := map[string]interface{}{
+		"serverArguments": map[string]interface{}{
+			"audit-log-format":    []interface{}{string("json")},
+			"audit-log-maxbackup": []interface{}{string("10")},
+			"audit-log-maxsize":   []interface{}{string("100")},
+			"audit-log-path":      []interface{}{string("/var/log/oauth-server/audit.log")},
+			"audit-policy-file":   []interface{}{string("/var/run/configmaps/audit/audit.yaml")},
+		},
+	}
+
+	for _, tt := range [...]struct {
+		name                     string
+		audit                    *configv1.Audit
+		unsupportedConfig        string
+		previouslyObservedConfig map[string]interface{}
+		expected                 map[string]interface{}
+		expectErr                bool
+		expectEvent              bool
+	}{
+		{
+			name:                     "APIServer not found",
+			previouslyObservedConfig: auditOpts,
+			expected:                 auditOpts,
+		},
+		{
+			name:                     "None",
+			audit:                    &configv1.Audit{Profile: configv1.NoneAuditProfileType},
+			previouslyObservedConfig: map[string]interface{}{},
+			expected:                 map[string]interface{}{},
+		},
+		{
+			name:                     "Default",
+			audit:                    &configv1.Audit{Profile: configv1.DefaultAuditProfileType},
+			previouslyObservedConfig: auditOpts,
+			expected:                 auditOpts,
+		},
+		{
+			name:                     "WriteRequestBodies",
+			audit:                    &configv1.Audit{Profile: configv1.WriteRequestBodiesAuditProfileType},
+			previouslyObservedConfig: auditOpts,
+			expected:                 withAuditPolicy(auditOpts, map[string]interface{}{"profile": "WriteRequestBodies"}),
+			expectEvent:              true,
+		},
+		{
+			name:                     "AllRequestBodies",
+			audit:                    &configv1.Audit{Profile: configv1.AllRequestBodiesAuditProfileType},
+			previouslyObservedConfig: withAuditPolicy(auditOpts, map[string]interface{}{"profile": "WriteRequestBodies"}),
+			expected:                 withAuditPolicy(auditOpts, map[string]interface{}{"profile": "AllRequestBodies"}),
+			expectEvent:              true,
+		},
+		{
+			name: "Default with custom rules",
+			audit: &configv1.Audit{
+				Profile: configv1.DefaultAuditProfileType,
+				CustomRules: []configv1.AuditCustomRule{
+					{Group: "system:authenticated:oauth", Profile: configv1.AllRequestBodiesAuditProfileType},
+				},
+			},
+			previouslyObservedConfig: auditOpts,
+			expected: withAuditPolicy(auditOpts, map[string]interface{}{
+				"profile": "Default",
+				"customRules": []interface{}{
+					map[string]interface{}{"group": "system:authenticated:oauth", "profile": "AllRequestBodies"},
+				},
+			}),
+			expectEvent: true,
+		},
+		{
+			name: "unknown custom rule profile",
+			audit: &configv1.Audit{
+				Profile: configv1.DefaultAuditProfileType,
+				CustomRules: []configv1.AuditCustomRule{
+					{Group: "system:authenticated:oauth", Profile: "Everything"},
+				},
+			},
+			previouslyObservedConfig: auditOpts,
+			expected:                 auditOpts,
+			expectErr:                true,
+		},
+		{
+			name:                     "back to the default policy",
+			audit:                    &configv1.Audit{Profile: configv1.DefaultAuditProfileType},
+			previouslyObservedConfig: withAuditPolicy(auditOpts, map[string]interface{}{"profile": "AllRequestBodies"}),
+			expected:                 auditOpts,
+			expectEvent:              true,
+		},
+		{
+			name:                     "unmanaged audit configmap",
+			audit:                    &configv1.Audit{Profile: configv1.WriteRequestBodiesAuditProfileType},
+			unsupportedConfig:        `{"operator":{"audit":{"manageConfigMap":false}}}`,
+			previouslyObservedConfig: auditOpts,
+			expected:                 auditOpts,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if tt.audit != nil {
+				if err := indexer.Add(&configv1.APIServer{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+					Spec:       configv1.APIServerSpec{Audit: *tt.audit},
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			operatorConfig := &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+			if len(tt.unsupportedConfig) > 0 {
+				operatorConfig.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)}
+			}
+			if err := operatorIndexer.Add(operatorConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				APIServerLister_:             configlistersv1.NewAPIServerLister(indexer),
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(operatorIndexer),
+			}
+
+			recorder := events.NewInMemoryRecorder(t.Name())
+			have, errs := oauth.ObserveAudit(listers, recorder, tt.previouslyObservedConfig)
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, have) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, have))
+			}
+
+			var gotEvent bool
+			for _, event := range recorder.Events() {
+				if event.Reason == "ObserveAuditPolicyProfile" {
+					gotEvent = true
+				}
+			}
+			if gotEvent != tt.expectEvent {
+				t.Errorf("expected audit policy profile event: %t, got events %v", tt.expectEvent, recorder.Events())
+			}
+		})
+	}
+}
+
+func TestAuditFallbackProfile(t *testing.T) {
+	auditOpts := map[string]interface{}{
+		"serverArguments": map[string]interface{}{
+			"audit-log-format":    []interface{}{string("json")},
+			"audit-log-maxbackup": []interface{}{string("10")},
+			"audit-log-maxsize":   []interface{}{string("100")},
+			"audit-log-path":      []interface{}{string("/var/log/oauth-server/audit.log")},
+			"audit-policy-file":   []interface{}{string("/var/run/configmaps/audit/audit.yaml")},
+		},
+	}
+
+	for _, tt := range [...]struct {
+		name              string
+		apiServerExists   bool
+		unsupportedConfig string
+		expected          map[string]interface{}
+		expectErr         bool
+	}{
+		{
+			name:     "Default by default",
+			expected: auditOpts,
+		},
+		{
+			name:              "None",
+			unsupportedConfig: `{"operator":{"audit":{"fallbackProfile":"None"}}}`,
+			expected:          map[string]interface{}{},
+		},
+		{
+			name:              "Default",
+			unsupportedConfig: `{"operator":{"audit":{"fallbackProfile":"Default"}}}`,
+			expected:          auditOpts,
+		},
+		{
+			name:              "WriteRequestBodies",
+			unsupportedConfig: `{"operator":{"audit":{"fallbackProfile":"WriteRequestBodies"}}}`,
+			expected:          withAuditPolicy(auditOpts, map[string]interface{}{"profile": "WriteRequestBodies"}),
+		},
+		{
+			name:              "AllRequestBodies",
+			unsupportedConfig: `{"operator":{"audit":{"fallbackProfile":"AllRequestBodies"}}}`,
+			expected:          withAuditPolicy(auditOpts, map[string]interface{}{"profile": "AllRequestBodies"}),
+		},
+		{
+			name:              "unknown profile",
+			unsupportedConfig: `{"operator":{"audit":{"fallbackProfile":"Everything"}}}`,
+			expected:          auditOpts,
+			expectErr:         true,
+		},
+		{
+			name:              "ignored when the APIServer config exists",
+			apiServerExists:   true,
+			unsupportedConfig: `{"operator":{"audit":{"fallbackProfile":"None"}}}`,
+			expected:          auditOpts,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if tt.apiServerExists {
+				if err := indexer.Add(&configv1.APIServer{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			operatorConfig := &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+			if len(tt.unsupportedConfig) > 0 {
+				operatorConfig.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)}
+			}
+			if err := operatorIndexer.Add(operatorConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				APIServerLister_:             configlistersv1.NewAPIServerLister(indexer),
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(operatorIndexer),
+			}
+
+			// the previously observed config has auditing enabled
+			have, errs := oauth.ObserveAudit(listers, events.NewInMemoryRecorder(t.Name()), auditOpts)
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, have) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, have))
+			}
+		})
+	}
+}
+
+func TestAuditAuthorizationDecisionsLevel(t *testing.T) {
+	auditOpts := map[string]interface{}{
+		"serverArguments": map[string]interface{}{
+			"audit-log-format":    []interface{}{string("json")},
+			"audit-log-maxbackup": []interface{}{string("10")},
+			"audit-log-maxsize":   []interface{}{string("100")},
+			"audit-log-path":      []interface{}{string("/var/log/oauth-server/audit.log")},
+			"audit-policy-file":   []interface{}{string("/var/run/configmaps/audit/audit.yaml")},
+		},
+	}
+
+	for _, tt := range [...]struct {
+		name              string
+		profile           configv1.AuditProfileType
+		unsupportedConfig string
+		expected          map[string]interface{}
+		expectErr         bool
+	}{
+		{
+			name:     "not set",
+			expected: auditOpts,
+		},
+		{
+			name:              "Metadata",
+			unsupportedConfig: `{"operator":{"audit":{"authorizationDecisionsLevel":"Metadata"}}}`,
+			expected:          withAuditPolicy(auditOpts, map[string]interface{}{"authorizationDecisionsLevel": "Metadata"}),
+		},
+		{
+			name:              "RequestResponse with a profile",
+			profile:           configv1.AllRequestBodiesAuditProfileType,
+			unsupportedConfig: `{"operator":{"audit":{"authorizationDecisionsLevel":"RequestResponse"}}}`,
+			expected:          withAuditPolicy(auditOpts, map[string]interface{}{"profile": "AllRequestBodies", "authorizationDecisionsLevel": "RequestResponse"}),
+		},
+		{
+			name:              "auditing off",
+			profile:           configv1.NoneAuditProfileType,
+			unsupportedConfig: `{"operator":{"audit":{"authorizationDecisionsLevel":"Metadata"}}}`,
+			expected:          map[string]interface{}{},
+		},
+		{
+			name:              "unmanaged audit configmap",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false,"authorizationDecisionsLevel":"Metadata"}}}`,
+			expected:          auditOpts,
+		},
+		{
+			name:              "None is not a level to capture at",
+			unsupportedConfig: `{"operator":{"audit":{"authorizationDecisionsLevel":"None"}}}`,
+			expected:          auditOpts,
+			expectErr:         true,
+		},
+		{
+			name:              "unknown level",
+			unsupportedConfig: `{"operator":{"audit":{"authorizationDecisionsLevel":"Everything"}}}`,
+			expected:          auditOpts,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(&configv1.APIServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Spec:       configv1.APIServerSpec{Audit: configv1.Audit{Profile: tt.profile}},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			operatorConfig := &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+			if len(tt.unsupportedConfig) > 0 {
+				operatorConfig.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)}
+			}
+			if err := operatorIndexer.Add(operatorConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				APIServerLister_:             configlistersv1.NewAPIServerLister(indexer),
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(operatorIndexer),
+			}
+
+			// the previously observed config has auditing enabled
+			have, errs := oauth.ObserveAudit(listers, events.NewInMemoryRecorder(t.Name()), auditOpts)
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, have) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, have))
+			}
+		})
+	}
+}
+
+func TestAuditRequestReceivedStage(t *testing.T) {
+	auditOpts := map[string]interface{}{
+		"serverArguments": map[string]interface{}{
+			"audit-log-format":    []interface{}{string("json")},
+			"audit-log-maxbackup": []interface{}{string("10")},
+			"audit-log-maxsize":   []interface{}{string("100")},
+			"audit-log-path":      []interface{}{string("/var/log/oauth-server/audit.log")},
+			"audit-policy-file":   []interface{}{string("/var/run/configmaps/audit/audit.yaml")},
+		},
+	}
+
+	for _, tt := range [...]struct {
+		name              string
+		profile           configv1.AuditProfileType
+		unsupportedConfig string
+		expected          map[string]interface{}
+		expectErr         bool
+	}{
+		{
+			name:     "omitted by default",
+			expected: auditOpts,
+		},
+		{
+			name:              "omitted explicitly",
+			unsupportedConfig: `{"operator":{"audit":{"includeRequestReceivedStage":false}}}`,
+			expected:          auditOpts,
+		},
+		{
+			name:              "included",
+			unsupportedConfig: `{"operator":{"audit":{"includeRequestReceivedStage":true}}}`,
+			expected:          withAuditPolicy(auditOpts, map[string]interface{}{"includeRequestReceivedStage": true}),
+		},
+		{
+			name:              "included with a profile",
+			profile:           configv1.WriteRequestBodiesAuditProfileType,
+			unsupportedConfig: `{"operator":{"audit":{"includeRequestReceivedStage":true}}}`,
+			expected:          withAuditPolicy(auditOpts, map[string]interface{}{"profile": "WriteRequestBodies", "includeRequestReceivedStage": true}),
+		},
+		{
+			name:              "auditing off",
+			profile:           configv1.NoneAuditProfileType,
+			unsupportedConfig: `{"operator":{"audit":{"includeRequestReceivedStage":true}}}`,
+			expected:          map[string]interface{}{},
+		},
+		{
+			name:              "unmanaged audit configmap",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false,"includeRequestReceivedStage":true}}}`,
+			expected:          auditOpts,
+		},
+		{
+			name:              "not a boolean",
+			unsupportedConfig: `{"operator":{"audit":{"includeRequestReceivedStage":"yes"}}}`,
+			expected:          auditOpts,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(&configv1.APIServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Spec:       configv1.APIServerSpec{Audit: configv1.Audit{Profile: tt.profile}},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			operatorConfig := &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+			if len(tt.unsupportedConfig) > 0 {
+				operatorConfig.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)}
+			}
+			if err := operatorIndexer.Add(operatorConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				APIServerLister_:             configlistersv1.NewAPIServerLister(indexer),
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(operatorIndexer),
+			}
+
+			have, errs := oauth.ObserveAudit(listers, events.NewInMemoryRecorder(t.Name()), auditOpts)
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, have) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, have))
+			}
+		})
+	}
+}
+
+// withAuditPolicy returns a copy of the observed config with the given auditPolicy
+func withAuditPolicy(observedConfig map[string]interface{}, auditPolicy map[string]interface{}) map[string]interface{} {
+	ret := runtime.DeepCopyJSON(observedConfig)
+	ret["auditPolicy"] = auditPolicy
+	return ret
+}
+
+func TestAuditWebhook(t *testing.T) {
+	auditOptsWithWebhook := func(webhookArgs map[string]interface{}) map[string]interface{} {
+		serverArguments := map[string]interface{}{
+			"audit-log-format":    []interface{}{string("json")},
+			"audit-log-maxbackup": []interface{}{string("10")},
+			"audit-log-maxsize":   []interface{}{string("100")},
+			"audit-log-path":      []interface{}{string("/var/log/oauth-server/audit.log")},
+			"audit-policy-file":   []interface{}{string("/var/run/configmaps/audit/audit.yaml")},
+		}
+		for name, value := range webhookArgs {
+			serverArguments[name] = value
+		}
+		return map[string]interface{}{"serverArguments": serverArguments}
+	}
+
+	for _, tt := range [...]struct {
+		name              string
+		unsupportedConfig string
+		expected          map[string]interface{}
+		expectErr         bool
+	}{
+		{
+			name:     "webhook off",
+			expected: auditOptsWithWebhook(nil),
+		},
+		{
+			name:              "throttle is omitted while the webhook is off",
+			unsupportedConfig: `{"operator":{"audit":{"webhook":{"throttleQPS":10,"throttleBurst":15}}}}`,
+			expected:          auditOptsWithWebhook(nil),
+		},
+		{
+			name:              "webhook without throttle",
+			unsupportedConfig: `{"operator":{"audit":{"webhook":{"configFile":"/var/run/configmaps/audit/webhook.kubeconfig"}}}}`,
+			expected: auditOptsWithWebhook(map[string]interface{}{
+				"audit-webhook-config-file": []interface{}{"/var/run/configmaps/audit/webhook.kubeconfig"},
+			}),
+		},
+		{
+			name:              "webhook with throttle",
+			unsupportedConfig: `{"operator":{"audit":{"webhook":{"configFile":"/var/run/configmaps/audit/webhook.kubeconfig","throttleQPS":2.5,"throttleBurst":15}}}}`,
+			expected: auditOptsWithWebhook(map[string]interface{}{
+				"audit-webhook-config-file":          []interface{}{"/var/run/configmaps/audit/webhook.kubeconfig"},
+				"audit-webhook-batch-throttle-qps":   []interface{}{"2.5"},
+				"audit-webhook-batch-throttle-burst": []interface{}{"15"},
+			}),
+		},
+		{
+			name:              "relative webhook config file",
+			unsupportedConfig: `{"operator":{"audit":{"webhook":{"configFile":"webhook.kubeconfig"}}}}`,
+			expected:          map[string]interface{}{},
+			expectErr:         true,
+		},
+		{
+			name:              "throttle is not a number",
+			unsupportedConfig: `{"operator":{"audit":{"webhook":{"configFile":"/var/run/configmaps/audit/webhook.kubeconfig","throttleQPS":"fast"}}}}`,
+			expected:          map[string]interface{}{},
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			operatorConfig := &operatorv1.Authentication{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			}
+			if len(tt.unsupportedConfig) > 0 {
+				operatorConfig.Spec.UnsupportedConfigOverrides.Raw = []byte(tt.unsupportedConfig)
+			}
+
+			operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := operatorIndexer.Add(operatorConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				APIServerLister_:             configlistersv1.NewAPIServerLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(operatorIndexer),
+			}
+
+			have, errs := oauth.ObserveAudit(listers, events.NewInMemoryRecorder(t.Name()), map[string]interface{}{})
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, have) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, have))
+			}
+		})
+	}
+}
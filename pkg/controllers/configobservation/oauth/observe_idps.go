@@ -36,7 +36,7 @@ func ObserveIdentityProviders(genericlisters configobserver.Listers, recorder ev
 		existingIDPsSlice = existingIdentityProviders.([]interface{})
 	}
 
-	existingSyncData, err := GetIDPConfigSyncData(existingConfig)
+	existingSyncData, err := idpConfigSyncData(existingConfig)
 	if err != nil {
 		return existingConfig, append(errs, err)
 	}
@@ -87,8 +87,17 @@ func ObserveIdentityProviders(genericlisters configobserver.Listers, recorder ev
 }
 
 // GetIDPConfigSyncData returns the data that should be synchronized and mounted
-// to the oauth-server container from the observed configuration
-func GetIDPConfigSyncData(observedConfig map[string]interface{}) (*datasync.ConfigSyncData, error) {
+// to the oauth-server container from the observed configuration. Identity
+// providers that are not reachable over TLS are refused unless allowed in
+// unsupportedConfigOverrides.operator.
+func GetIDPConfigSyncData(observedConfig, unsupportedConfig map[string]interface{}) (*datasync.ConfigSyncData, error) {
+	if err := validateIdentityProviderTransport(observedConfig, unsupportedConfig); err != nil {
+		return nil, err
+	}
+	return idpConfigSyncData(observedConfig)
+}
+
+func idpConfigSyncData(observedConfig map[string]interface{}) (*datasync.ConfigSyncData, error) {
 	currentSyncDataUnstructured, _, err := unstructured.NestedFieldCopy(observedConfig, identityProvidersMounts...)
 	if err != nil {
 		return nil, err
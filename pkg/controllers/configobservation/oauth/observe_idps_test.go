@@ -228,3 +228,80 @@ func eventsReasonMessage(e []*corev1.Event) []string {
 	}
 	return reasonMessages
 }
+
+func TestGetIDPConfigSyncDataInsecureURLs(t *testing.T) {
+	requestHeaderIDP := func(loginURL string) *configv1.OAuth {
+		return &configv1.OAuth{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec: configv1.OAuthSpec{
+				IdentityProviders: []configv1.IdentityProvider{
+					{
+						Name: "some request header provider",
+						IdentityProviderConfig: configv1.IdentityProviderConfig{
+							Type: configv1.IdentityProviderTypeRequestHeader,
+							RequestHeader: &configv1.RequestHeaderIdentityProvider{
+								LoginURL: loginURL,
+								Headers:  []string{"X-Remote-User"},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		config            *configv1.OAuth
+		unsupportedConfig map[string]interface{}
+		expectedError     string
+	}{
+		{
+			name:   "secure IdP is allowed",
+			config: requestHeaderIDP("https://login.example.com/login"),
+		},
+		{
+			name:          "insecure IdP is rejected",
+			config:        requestHeaderIDP("http://login.example.com/login"),
+			expectedError: `identity provider "some request header provider": loginURL "http://login.example.com/login" does not use https (set identityProviders.allowInsecureURLs in unsupportedConfigOverrides to allow them)`,
+		},
+		{
+			name:              "insecure IdP is allowed when opted out",
+			config:            requestHeaderIDP("http://login.example.com/login"),
+			unsupportedConfig: map[string]interface{}{"identityProviders": map[string]interface{}{"allowInsecureURLs": true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(tt.config); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				ConfigMapLister: corelistersv1.NewConfigMapLister(indexer),
+				SecretsLister:   corelistersv1.NewSecretLister(indexer),
+				OAuthLister_:    configlistersv1.NewOAuthLister(indexer),
+				ResourceSync:    &mockResourceSyncer{t: t, synced: map[string]string{}},
+			}
+
+			// the IdP is always observed, the transport is only checked for the deployment
+			observedConfig, errs := ObserveIdentityProviders(listers, events.NewInMemoryRecorder(t.Name()), map[string]interface{}{})
+			if len(errs) > 0 {
+				t.Fatalf("unexpected observation errors: %v", errs)
+			}
+			if _, found := observedConfig["oauthConfig"]; !found {
+				t.Fatalf("expected the IdP to be observed, got %v", observedConfig)
+			}
+
+			_, err := GetIDPConfigSyncData(observedConfig, tt.unsupportedConfig)
+			gotError := ""
+			if err != nil {
+				gotError = err.Error()
+			}
+			if gotError != tt.expectedError {
+				t.Errorf("expected error %q, got %q", tt.expectedError, gotError)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/openshift/library-go/pkg/operator/configobserver"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
+)
+
+var (
+	sessionMaxAgeSecondsPath = []string{"oauthConfig", "sessionConfig", "sessionMaxAgeSeconds"}
+	sessionMaxAgePath        = []string{"session", "maxAge"}
+)
+
+// ObserveSessionConfig observes the lifetime of the browser session of the
+// oauth-server. It is set as a duration (e.g. "10m") in unsupportedConfigOverrides
+// (operator.session.maxAge) and is independent of the token lifetimes.
+//
+// The raw oauthServer.oauthConfig.sessionConfig.sessionMaxAgeSeconds override is
+// merged over the observed config by the payload config controller, so it always
+// wins. session.maxAge is not observed at all while it is set, so that the
+// observed config does not carry a value that never takes effect.
+func ObserveSessionConfig(genericlisters configobserver.Listers, recorder events.Recorder, existingConfig map[string]interface{}) (ret map[string]interface{}, errs []error) {
+	defer func() {
+		ret = configobserver.Pruned(ret, sessionMaxAgeSecondsPath)
+	}()
+
+	listers := genericlisters.(configobservation.Listers)
+	errs = []error{}
+
+	existingMaxAgeSeconds, _, err := unstructured.NestedFloat64(existingConfig, sessionMaxAgeSecondsPath...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	unsupportedConfig, err := listers.UnsupportedConfigOverrides()
+	if err != nil {
+		return existingConfig, append(errs, fmt.Errorf(
+			"failed to get unsupportedConfigOverrides of operator.openshift.io/cluster: %w",
+			err,
+		))
+	}
+
+	overridden, err := rawSessionMaxAgeOverridden(listers)
+	if err != nil {
+		return existingConfig, append(errs, err)
+	}
+
+	var maxAgeSeconds float64
+	if !overridden {
+		maxAgeSeconds, err = sessionMaxAgeSeconds(unsupportedConfig)
+		if err != nil {
+			return existingConfig, append(errs, err)
+		}
+	}
+
+	observedConfig := map[string]interface{}{}
+	if maxAgeSeconds > 0 {
+		if err := unstructured.SetNestedField(observedConfig, maxAgeSeconds, sessionMaxAgeSecondsPath...); err != nil {
+			return existingConfig, append(errs, err)
+		}
+	}
+
+	if existingMaxAgeSeconds != maxAgeSeconds {
+		recorder.Eventf("ObserveSessionConfig", "sessionMaxAgeSeconds changed from %v to %v", existingMaxAgeSeconds, maxAgeSeconds)
+	}
+
+	return observedConfig, errs
+}
+
+// rawSessionMaxAgeOverridden returns whether sessionMaxAgeSeconds is set in the
+// raw oauthServer config of unsupportedConfigOverrides
+func rawSessionMaxAgeOverridden(listers configobservation.Listers) (bool, error) {
+	if listers.AuthenticationOperatorLister == nil {
+		return false, nil
+	}
+
+	operatorConfig, err := listers.AuthenticationOperatorLister.Get("cluster")
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if len(operatorConfig.Spec.UnsupportedConfigOverrides.Raw) == 0 {
+		return false, nil
+	}
+	rawConfig, err := common.UnstructuredConfigFrom(operatorConfig.Spec.UnsupportedConfigOverrides.Raw, configobservation.OAuthServerConfigPrefix)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the unsupportedConfigOverrides prefix %q: %w", configobservation.OAuthServerConfigPrefix, err)
+	}
+
+	oauthServerConfig := map[string]interface{}{}
+	if err := json.Unmarshal(rawConfig, &oauthServerConfig); err != nil {
+		return false, err
+	}
+
+	_, found, err := unstructured.NestedFieldNoCopy(oauthServerConfig, sessionMaxAgeSecondsPath...)
+	return found, err
+}
+
+// sessionMaxAgeSeconds returns the configured session max-age in seconds, or
+// 0 when it is not set
+func sessionMaxAgeSeconds(unsupportedConfig map[string]interface{}) (float64, error) {
+	fieldName := strings.Join(sessionMaxAgePath, ".")
+
+	maxAge, found, err := unstructured.NestedString(unsupportedConfig, sessionMaxAgePath...)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read %s: %w", fieldName, err)
+	}
+	if !found || len(maxAge) == 0 {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(maxAge)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration: %w", fieldName, err)
+	}
+	if duration < time.Second {
+		return 0, fmt.Errorf("%s must be at least 1s, got %q", fieldName, maxAge)
+	}
+	if duration%time.Second != 0 {
+		return 0, fmt.Errorf("%s must be a whole number of seconds, got %q", fieldName, maxAge)
+	}
+
+	return duration.Seconds(), nil
+}
@@ -0,0 +1,140 @@
+package oauth
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	operatorlistersv1 "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
+)
+
+func TestObserveSessionConfig(t *testing.T) {
+	tests := []struct {
+		name                     string
+		unsupportedConfig        string
+		previouslyObservedConfig map[string]interface{}
+		expected                 map[string]interface{}
+		expectErr                bool
+	}{
+		{
+			name:                     "not configured",
+			previouslyObservedConfig: map[string]interface{}{},
+			expected:                 map[string]interface{}{},
+		},
+		{
+			name:                     "valid session max-age",
+			unsupportedConfig:        `{"operator":{"session":{"maxAge":"10m"}}}`,
+			previouslyObservedConfig: map[string]interface{}{},
+			expected: map[string]interface{}{
+				"oauthConfig": map[string]interface{}{
+					"sessionConfig": map[string]interface{}{
+						"sessionMaxAgeSeconds": float64(600),
+					},
+				},
+			},
+		},
+		{
+			name:              "independent of token config",
+			unsupportedConfig: `{"operator":{"session":{"maxAge":"1h"}}}`,
+			previouslyObservedConfig: map[string]interface{}{
+				"oauthConfig": map[string]interface{}{
+					"tokenConfig": map[string]interface{}{
+						"accessTokenMaxAgeSeconds": float64(86400),
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"oauthConfig": map[string]interface{}{
+					"sessionConfig": map[string]interface{}{
+						"sessionMaxAgeSeconds": float64(3600),
+					},
+				},
+			},
+		},
+		{
+			name:              "invalid duration keeps the previous config",
+			unsupportedConfig: `{"operator":{"session":{"maxAge":"ten minutes"}}}`,
+			previouslyObservedConfig: map[string]interface{}{
+				"oauthConfig": map[string]interface{}{
+					"sessionConfig": map[string]interface{}{
+						"sessionMaxAgeSeconds": float64(600),
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"oauthConfig": map[string]interface{}{
+					"sessionConfig": map[string]interface{}{
+						"sessionMaxAgeSeconds": float64(600),
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name:              "raw oauthServer override takes precedence",
+			unsupportedConfig: `{"oauthServer":{"oauthConfig":{"sessionConfig":{"sessionMaxAgeSeconds":300}}},"operator":{"session":{"maxAge":"10m"}}}`,
+			previouslyObservedConfig: map[string]interface{}{
+				"oauthConfig": map[string]interface{}{
+					"sessionConfig": map[string]interface{}{
+						"sessionMaxAgeSeconds": float64(600),
+					},
+				},
+			},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:                     "session knob at the top level is ignored",
+			unsupportedConfig:        `{"session":{"maxAge":"10m"}}`,
+			previouslyObservedConfig: map[string]interface{}{},
+			expected:                 map[string]interface{}{},
+		},
+		{
+			name:                     "fractional seconds",
+			unsupportedConfig:        `{"operator":{"session":{"maxAge":"1500ms"}}}`,
+			previouslyObservedConfig: map[string]interface{}{},
+			expected:                 map[string]interface{}{},
+			expectErr:                true,
+		},
+		{
+			name:                     "negative duration",
+			unsupportedConfig:        `{"operator":{"session":{"maxAge":"-5m"}}}`,
+			previouslyObservedConfig: map[string]interface{}{},
+			expected:                 map[string]interface{}{},
+			expectErr:                true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			operatorConfig := &operatorv1.Authentication{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+			if len(tt.unsupportedConfig) > 0 {
+				operatorConfig.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)}
+			}
+			if err := indexer.Add(operatorConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			listers := configobservation.Listers{
+				AuthenticationOperatorLister: operatorlistersv1.NewAuthenticationLister(indexer),
+			}
+
+			got, errs := ObserveSessionConfig(listers, events.NewInMemoryRecorder(t.Name()), tt.previouslyObservedConfig)
+			if tt.expectErr != (len(errs) > 0) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, errs)
+			}
+
+			if !equality.Semantic.DeepEqual(tt.expected, got) {
+				t.Errorf("result does not match expected config: %s", cmp.Diff(tt.expected, got))
+			}
+		})
+	}
+}
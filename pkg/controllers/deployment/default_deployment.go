@@ -1,17 +1,28 @@
 package deployment
 
 import (
+	"bytes"
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ghodss/yaml"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 
 	configv1 "github.com/openshift/api/config/v1"
@@ -26,23 +37,90 @@ import (
 	"github.com/openshift/cluster-authentication-operator/pkg/operator/datasync"
 )
 
+// oauthServerContainerName is the name of the oauth-server container in the
+// rendered deployment. It is used both to render the deployment asset and to
+// look the container up again.
+const oauthServerContainerName = "oauth-openshift"
+
+// restartContentHashAnnotation holds the hash of the content of the resources
+// configured in deployment.restartOnContentChange
+const restartContentHashAnnotation = "operator.openshift.io/restart-content-hash"
+
+// logLevelAnnotation holds the verbosity of the oauth-server so that a change
+// of the log level rolls the pods out
+const logLevelAnnotation = "operator.openshift.io/log-level"
+
+// auditLogPathAnnotation tells log collectors where on the host the
+// oauth-server writes its audit log
+const auditLogPathAnnotation = "operator.openshift.io/audit-log-path"
+
+var (
+	// deploymentAsset is the parsed deployment asset, it must not be modified
+	deploymentAsset     *appsv1.Deployment
+	deploymentAssetOnce sync.Once
+
+	auditComplianceModePath      = []string{"audit", "complianceMode"}
+	guaranteedQoSPath            = []string{"deployment", "guaranteedQoS"}
+	antiAffinityWeightPath       = []string{"deployment", "antiAffinityWeight"}
+	honorDefaultNodeSelectorPath = []string{"deployment", "honorDefaultNodeSelector"}
+	restartOnContentChangePath   = []string{"deployment", "restartOnContentChange"}
+	logForwardingEnabledPath     = []string{"audit", "logForwarding", "enabled"}
+	ephemeralStorageRequestPath  = []string{"deployment", "ephemeralStorage", "request"}
+	ephemeralStorageLimitPath    = []string{"deployment", "ephemeralStorage", "limit"}
+	deploymentStrategyPath       = []string{"deployment", "strategy"}
+	startupStaggerPath           = []string{"deployment", "startupStaggerSeconds"}
+	sidecarInjectionPath         = []string{"deployment", "sidecarInjectionAnnotations"}
+	auditLogVolumeTypePath       = []string{"audit", "logVolume", "type"}
+	auditLogHostPathPath         = []string{"audit", "logVolume", "hostPath"}
+
+	// defaultSidecarInjectionAnnotations opt the oauth-server pods out of
+	// service mesh sidecar injection, as sidecars would break the TLS the
+	// oauth-server serves itself
+	defaultSidecarInjectionAnnotations = map[string]string{
+		"sidecar.istio.io/inject": "false",
+		"linkerd.io/inject":       "disabled",
+	}
+
+	// defaultEphemeralStorageRequest covers the container logs the kubelet keeps
+	// around. The audit logs only count towards the ephemeral storage of the pod
+	// with an EmptyDir audit log volume, their size is added on top then.
+	defaultEphemeralStorageRequest = resource.MustParse("50Mi")
+	logForwardingAnnotationsPath   = []string{"audit", "logForwarding", "annotations"}
+	guaranteedQoSResourceNames     = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+)
+
 func getOAuthServerDeployment(
 	operatorConfig *operatorv1.Authentication,
 	proxyConfig *configv1.Proxy,
+	schedulerConfig *configv1.Scheduler,
+	infrastructureConfig *configv1.Infrastructure,
 	bootstrapUserExists bool,
 	resourceVersions ...string,
 ) (*appsv1.Deployment, error) {
+	unsupportedConfig, err := common.UnsupportedConfigOverrides(&operatorConfig.Spec.OperatorSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode unsupportedConfigOverrides: %w", err)
+	}
+
 	// load deployment
-	deployment := resourceread.ReadDeploymentV1OrDie(bindata.MustAsset("oauth-openshift/deployment.yaml"))
+	deployment := renderFromCachedAsset()
+
+	if err := setDeploymentStrategy(&deployment.Spec, infrastructureConfig, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	if err := setStartupStagger(&deployment.Spec, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	if err := setRouteAdmissionReadinessGate(&deployment.Spec.Template.Spec, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	logLevel := getLogLevel(operatorConfig.Spec.LogLevel)
 
 	// force redeploy when any associated resource changes
-	// we use a hash to prevent this value from growing indefinitely
-	// need to sort first in order to get a stable array
-	sort.Strings(resourceVersions)
-	rvs := strings.Join(resourceVersions, ",")
-	klog.V(4).Infof("tracked resource versions: %s", rvs)
-	rvsHash := sha512.Sum512([]byte(rvs))
-	rvsHashStr := base64.RawURLEncoding.EncodeToString(rvsHash[:])
+	rvsHashStr := resourceVersionsHash(resourceVersions...)
 	if deployment.Annotations == nil {
 		deployment.Annotations = map[string]string{}
 	}
@@ -52,6 +130,7 @@ func getOAuthServerDeployment(
 		deployment.Spec.Template.Annotations = map[string]string{}
 	}
 	deployment.Spec.Template.Annotations["operator.openshift.io/rvs-hash"] = rvsHashStr
+	deployment.Spec.Template.Annotations[logLevelAnnotation] = fmt.Sprintf("%d", logLevel)
 
 	// Ensure a rollout when the bootstrap user goes away
 	if bootstrapUserExists {
@@ -59,7 +138,10 @@ func getOAuthServerDeployment(
 	}
 
 	templateSpec := &deployment.Spec.Template.Spec
-	container := &templateSpec.Containers[0]
+	container, err := oauthServerContainer(templateSpec)
+	if err != nil {
+		return nil, err
+	}
 
 	// image spec
 	if container.Image == "${IMAGE}" {
@@ -70,7 +152,31 @@ func getOAuthServerDeployment(
 	container.Env = append(container.Env, proxyConfigToEnvVars(proxyConfig)...)
 
 	// set log level
-	container.Args[0] = strings.Replace(container.Args[0], "${LOG_LEVEL}", fmt.Sprintf("%d", getLogLevel(operatorConfig.Spec.LogLevel)), -1)
+	container.Args[0] = strings.Replace(container.Args[0], "${LOG_LEVEL}", fmt.Sprintf("%d", logLevel), -1)
+
+	if err := setAuditPolicyMountPath(container, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	if err := setAuditPolicyConfigMapOptional(templateSpec, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	if err := restrictAuditLogPermissions(container, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	if err := setGuaranteedQoS(templateSpec, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	if err := setAntiAffinityWeight(templateSpec, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
+	if err := applyDefaultNodeSelector(templateSpec, schedulerConfig, unsupportedConfig); err != nil {
+		return nil, err
+	}
 
 	observedConfig, err := common.UnstructuredConfigFrom(
 		operatorConfig.Spec.ObservedConfig.Raw,
@@ -84,7 +190,7 @@ func getOAuthServerDeployment(
 		)
 	}
 
-	idpSyncData, err := getSyncDataFromOperatorConfig(observedConfig)
+	idpSyncData, err := getSyncDataFromOperatorConfig(observedConfig, unsupportedConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get IDP sync data: %v", err)
 	}
@@ -107,6 +213,35 @@ func getOAuthServerDeployment(
 		return nil, fmt.Errorf("unable to parse raw server arguments: %w", err)
 	}
 
+	if err := auditWebhookThrottle(args); err != nil {
+		return nil, err
+	}
+
+	auditLogSize, err := setAuditLogVolume(templateSpec, unsupportedConfig, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setEphemeralStorage(container, unsupportedConfig, auditLogSize); err != nil {
+		return nil, err
+	}
+
+	if err := validateServerArgumentPaths(container, unsupportedConfig, args); err != nil {
+		return nil, err
+	}
+
+	if err := validateAuditPolicyFile(templateSpec, container, unsupportedConfig, args); err != nil {
+		return nil, err
+	}
+
+	if err := setLogForwardingAnnotations(&deployment.Spec.Template, unsupportedConfig, args); err != nil {
+		return nil, err
+	}
+
+	if err := setSidecarInjectionAnnotations(&deployment.Spec.Template.ObjectMeta, unsupportedConfig); err != nil {
+		return nil, err
+	}
+
 	container.Args[0] = strings.Replace(
 		container.Args[0],
 		"${SERVER_ARGUMENTS}",
@@ -114,16 +249,593 @@ func getOAuthServerDeployment(
 		1,
 	)
 
+	if err := validatePodSpec(templateSpec); err != nil {
+		return nil, err
+	}
+
 	return deployment, nil
 }
 
-func getSyncDataFromOperatorConfig(observedConfig []byte) (*datasync.ConfigSyncData, error) {
+// renderFromCachedAsset returns a copy of the parsed deployment asset. The
+// asset is only parsed once, and every call returns a fresh deep copy that the
+// caller is free to modify, so that concurrent renders never share state.
+func renderFromCachedAsset() *appsv1.Deployment {
+	deploymentAssetOnce.Do(func() {
+		deploymentAsset = resourceread.ReadDeploymentV1OrDie(bytes.ReplaceAll(
+			bindata.MustAsset("oauth-openshift/deployment.yaml"),
+			[]byte("${CONTAINER_NAME}"),
+			[]byte(oauthServerContainerName),
+		))
+	})
+	return deploymentAsset.DeepCopy()
+}
+
+// resourceVersionsHash computes the value of the rvs-hash annotation of the
+// deployment for the given resource versions without rendering the deployment.
+// Controllers can compare it with the annotation of the current deployment to
+// tell whether a rollout would be needed. The log level is not part of the
+// hash, it is tracked by the logLevelAnnotation of the pod template.
+func resourceVersionsHash(resourceVersions ...string) string {
+	// we use a hash to prevent this value from growing indefinitely
+	// need to sort first in order to get a stable array
+	sorted := make([]string, len(resourceVersions))
+	copy(sorted, resourceVersions)
+	sort.Strings(sorted)
+	rvs := strings.Join(sorted, ",")
+	klog.V(4).Infof("tracked resource versions: %s", rvs)
+	rvsHash := sha512.Sum512([]byte(rvs))
+	return base64.RawURLEncoding.EncodeToString(rvsHash[:])
+}
+
+// restrictAuditLogPermissions makes the oauth-server create its audit logs
+// with mode 0600 and the log directory with mode 0700 when audit.complianceMode
+// is set in unsupportedConfigOverrides.operator. The audit log directory is a hostPath
+// volume by default, which fsGroup does not apply to, and the pod sets no
+// fsGroup for an EmptyDir either. Hence the permissions are enforced via the
+// umask of the server process and by tightening whatever previous runs, or the
+// kubelet creating the EmptyDir world-writable, left behind.
+func restrictAuditLogPermissions(container *corev1.Container, unsupportedConfig map[string]interface{}) error {
+	complianceMode, _, err := unstructured.NestedBool(unsupportedConfig, auditComplianceModePath...)
+	if err != nil {
+		return fmt.Errorf("unable to read audit.complianceMode: %w", err)
+	}
+	if !complianceMode {
+		return nil
+	}
+
+	var auditLogDir string
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == "audit-dir" {
+			auditLogDir = mount.MountPath
+		}
+	}
+	if len(auditLogDir) == 0 {
+		return fmt.Errorf("unable to restrict audit log permissions: no audit-dir volume mount found")
+	}
+
+	container.Args[0] = strings.Replace(
+		container.Args[0],
+		"exec oauth-server",
+		fmt.Sprintf("umask 0077\nchmod -R go-rwx %s\nexec oauth-server", auditLogDir),
+		1,
+	)
+
+	return nil
+}
+
+// setAuditLogVolume decides where the audit-dir volume the oauth-server writes
+// its audit logs to lives. By default it is the hostPath from the deployment
+// asset so that node-local collectors pick the logs up. The hostPath stays the
+// default, rather than an EmptyDir, because that is what the audit-dir volume
+// of the deployment asset has always been. audit.logVolume.type in
+// unsupportedConfigOverrides.operator switches between HostPath and EmptyDir, and
+// audit.logVolume.hostPath moves the logs to another directory under /var/log
+// on the host. The directory is mounted read-write into a privileged
+// container, hence it must be a clean absolute path below /var/log.
+//
+// An EmptyDir is limited to the size the rotated audit logs can grow to, which
+// is returned so that it can be added to the ephemeral-storage request of the
+// container. The size is nil for a hostPath.
+func setAuditLogVolume(podSpec *corev1.PodSpec, unsupportedConfig map[string]interface{}, args arguments.ServerArguments) (*resource.Quantity, error) {
+	volumeType, _, err := unstructured.NestedString(unsupportedConfig, auditLogVolumeTypePath...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", strings.Join(auditLogVolumeTypePath, "."), err)
+	}
+	hostPath, _, err := unstructured.NestedString(unsupportedConfig, auditLogHostPathPath...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", strings.Join(auditLogHostPathPath, "."), err)
+	}
+
+	var volume *corev1.Volume
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == "audit-dir" {
+			volume = &podSpec.Volumes[i]
+		}
+	}
+	if volume == nil {
+		return nil, fmt.Errorf("unable to configure the audit log volume: no audit-dir volume found")
+	}
+
+	switch volumeType {
+	case "", "HostPath":
+		if len(hostPath) == 0 {
+			return nil, nil
+		}
+		if err := validateAuditLogHostPath(hostPath); err != nil {
+			return nil, err
+		}
+		volume.VolumeSource = corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{Path: hostPath},
+		}
+		return nil, nil
+	case "EmptyDir":
+		if len(hostPath) > 0 {
+			return nil, fmt.Errorf("%s cannot be set with an EmptyDir audit log volume", strings.Join(auditLogHostPathPath, "."))
+		}
+		sizeLimit, err := auditLogSizeLimit(args)
+		if err != nil {
+			return nil, err
+		}
+		volume.VolumeSource = corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: sizeLimit},
+		}
+		return sizeLimit, nil
+	default:
+		return nil, fmt.Errorf("unsupported %s %q, must be one of HostPath, EmptyDir", strings.Join(auditLogVolumeTypePath, "."), volumeType)
+	}
+}
+
+// auditLogSizeLimit returns the space the audit log and its rotated backups
+// take up at most, i.e. audit-log-maxsize megabytes for the current file and
+// every one of the audit-log-maxbackup backups. Both have to be positive as
+// the logs are not bounded otherwise.
+func auditLogSizeLimit(args arguments.ServerArguments) (*resource.Quantity, error) {
+	maxSize, err := positiveServerArgument(args, "audit-log-maxsize")
+	if err != nil {
+		return nil, fmt.Errorf("unable to bound the EmptyDir audit log volume: %w", err)
+	}
+	maxBackup, err := positiveServerArgument(args, "audit-log-maxbackup")
+	if err != nil {
+		return nil, fmt.Errorf("unable to bound the EmptyDir audit log volume: %w", err)
+	}
+
+	return resource.NewQuantity(maxSize*(maxBackup+1)*1024*1024, resource.BinarySI), nil
+}
+
+// positiveServerArgument returns the value of the single-valued integer server argument
+func positiveServerArgument(args arguments.ServerArguments, name string) (int64, error) {
+	values := args[name]
+	if len(values) != 1 {
+		return 0, fmt.Errorf("expected a single %s server argument, got %q", name, values)
+	}
+	value, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %d", name, value)
+	}
+	return value, nil
+}
+
+// setAuditPolicyMountPath mounts the audit configmap to the directory that the
+// audit-policy-file server argument is observed from
+func setAuditPolicyMountPath(container *corev1.Container, unsupportedConfig map[string]interface{}) error {
+	mountPath, err := observeoauth.AuditPolicyMountPath(unsupportedConfig)
+	if err != nil {
+		return err
+	}
+
+	for i := range container.VolumeMounts {
+		if container.VolumeMounts[i].Name == "audit-policies" {
+			container.VolumeMounts[i].MountPath = mountPath
+			return nil
+		}
+	}
+	return fmt.Errorf("unable to configure the audit policy mount path: no audit-policies volume mount found")
+}
+
+// setAuditPolicyConfigMapOptional marks the audit configmap volume optional
+// when the configmap is not managed by the operator. The operator does not
+// create the configmap then, and a policy file provided by other means
+// (audit.policyFile) must not leave the pods stuck waiting for it.
+func setAuditPolicyConfigMapOptional(podSpec *corev1.PodSpec, unsupportedConfig map[string]interface{}) error {
+	managed, err := observeoauth.AuditConfigMapManaged(unsupportedConfig)
+	if err != nil || managed {
+		return err
+	}
+
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == "audit-policies" && podSpec.Volumes[i].ConfigMap != nil {
+			optional := true
+			podSpec.Volumes[i].ConfigMap.Optional = &optional
+			return nil
+		}
+	}
+	return fmt.Errorf("unable to make the audit configmap optional: no audit-policies configmap volume found")
+}
+
+// validateAuditLogHostPath makes sure that the audit logs can only be moved to
+// a dedicated directory below /var/log on the host
+func validateAuditLogHostPath(hostPath string) error {
+	switch {
+	case !filepath.IsAbs(hostPath):
+		return fmt.Errorf("audit log host path %q must be absolute", hostPath)
+	case filepath.Clean(hostPath) != hostPath:
+		return fmt.Errorf("audit log host path %q must be clean, i.e. %q", hostPath, filepath.Clean(hostPath))
+	case !strings.HasPrefix(hostPath, "/var/log/"):
+		return fmt.Errorf("audit log host path %q must be a directory below /var/log", hostPath)
+	}
+	return nil
+}
+
+// auditLogHostPath returns where on the host the file the oauth-server writes
+// to containerPath ends up, if it is on the host at all
+func auditLogHostPath(podSpec *corev1.PodSpec, containerPath string) (string, bool) {
+	container, err := oauthServerContainer(podSpec)
+	if err != nil {
+		return "", false
+	}
+
+	var mountPath string
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == "audit-dir" {
+			mountPath = mount.MountPath
+		}
+	}
+	relPath, err := filepath.Rel(mountPath, containerPath)
+	if len(mountPath) == 0 || err != nil || strings.HasPrefix(relPath, "..") {
+		return "", false
+	}
+
+	for _, volume := range podSpec.Volumes {
+		if volume.Name == "audit-dir" && volume.HostPath != nil {
+			return filepath.Join(volume.HostPath.Path, relPath), true
+		}
+	}
+	return "", false
+}
+
+// setEphemeralStorage sets the ephemeral-storage request and limit of the
+// oauth-server container. The request defaults to defaultEphemeralStorageRequest,
+// both can be set via deployment.ephemeralStorage.request and
+// deployment.ephemeralStorage.limit in unsupportedConfigOverrides.operator. There is no
+// default limit so that pods are not evicted by surprise. The size of an
+// EmptyDir audit log volume is added to the request as the audit logs count
+// towards the ephemeral storage of the pod then.
+func setEphemeralStorage(container *corev1.Container, unsupportedConfig map[string]interface{}, auditLogSize *resource.Quantity) error {
+	request, err := quantityFromConfig(unsupportedConfig, ephemeralStorageRequestPath)
+	if err != nil {
+		return err
+	}
+	if request == nil {
+		defaultRequest := defaultEphemeralStorageRequest.DeepCopy()
+		request = &defaultRequest
+	}
+	if auditLogSize != nil {
+		request.Add(*auditLogSize)
+	}
+
+	limit, err := quantityFromConfig(unsupportedConfig, ephemeralStorageLimitPath)
+	if err != nil {
+		return err
+	}
+	if limit != nil && limit.Cmp(*request) < 0 {
+		return fmt.Errorf("%s (%s) must not be lower than %s (%s)",
+			strings.Join(ephemeralStorageLimitPath, "."), limit.String(),
+			strings.Join(ephemeralStorageRequestPath, "."), request.String(),
+		)
+	}
+
+	if container.Resources.Requests == nil {
+		container.Resources.Requests = corev1.ResourceList{}
+	}
+	container.Resources.Requests[corev1.ResourceEphemeralStorage] = request.DeepCopy()
+
+	if limit != nil {
+		if container.Resources.Limits == nil {
+			container.Resources.Limits = corev1.ResourceList{}
+		}
+		container.Resources.Limits[corev1.ResourceEphemeralStorage] = limit.DeepCopy()
+	}
+
+	return nil
+}
+
+// quantityFromConfig returns the resource quantity at fieldPath, or nil if unset
+func quantityFromConfig(unsupportedConfig map[string]interface{}, fieldPath []string) (*resource.Quantity, error) {
+	fieldName := strings.Join(fieldPath, ".")
+
+	value, found, err := unstructured.NestedString(unsupportedConfig, fieldPath...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", fieldName, err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fieldName, err)
+	}
+	if quantity.Sign() <= 0 {
+		return nil, fmt.Errorf("%s must be positive, got %q", fieldName, value)
+	}
+	return &quantity, nil
+}
+
+// setGuaranteedQoS makes the requests and limits of the CPU and memory of all
+// containers of the pod equal when deployment.guaranteedQoS is set in
+// unsupportedConfigOverrides.operator, so that the pod gets the Guaranteed QoS class.
+// Limits win over requests when both are set.
+func setGuaranteedQoS(podSpec *corev1.PodSpec, unsupportedConfig map[string]interface{}) error {
+	guaranteed, _, err := unstructured.NestedBool(unsupportedConfig, guaranteedQoSPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(guaranteedQoSPath, "."), err)
+	}
+	if !guaranteed {
+		return nil
+	}
+
+	for i := range podSpec.Containers {
+		resources := &podSpec.Containers[i].Resources
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		if resources.Limits == nil {
+			resources.Limits = corev1.ResourceList{}
+		}
+
+		for _, name := range guaranteedQoSResourceNames {
+			if limit, ok := resources.Limits[name]; ok {
+				resources.Requests[name] = limit.DeepCopy()
+			} else if request, ok := resources.Requests[name]; ok {
+				resources.Limits[name] = request.DeepCopy()
+			} else {
+				return fmt.Errorf("unable to set the Guaranteed QoS class: container %q has no %s request or limit", podSpec.Containers[i].Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setAntiAffinityWeight sets the weight of the preferred pod anti-affinity that
+// spreads the oauth-server pods across nodes to the value of
+// deployment.antiAffinityWeight in unsupportedConfigOverrides.operator, if set.
+func setAntiAffinityWeight(podSpec *corev1.PodSpec, unsupportedConfig map[string]interface{}) error {
+	fieldName := strings.Join(antiAffinityWeightPath, ".")
+
+	weight, found, err := unstructured.NestedFloat64(unsupportedConfig, antiAffinityWeightPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", fieldName, err)
+	}
+	if !found {
+		return nil
+	}
+	if weight != float64(int32(weight)) || weight < 1 || weight > 100 {
+		return fmt.Errorf("%s must be an integer in the range 1-100, got %v", fieldName, weight)
+	}
+
+	if podSpec.Affinity == nil || podSpec.Affinity.PodAntiAffinity == nil {
+		return fmt.Errorf("unable to set %s: the pod spec has no pod anti-affinity", fieldName)
+	}
+	terms := podSpec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	for i := range terms {
+		if terms[i].PodAffinityTerm.TopologyKey == corev1.LabelHostname {
+			terms[i].Weight = int32(weight)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unable to set %s: the pod spec has no preferred pod anti-affinity on %s", fieldName, corev1.LabelHostname)
+}
+
+// setDeploymentStrategy sets the rollout strategy of the deployment. It can be
+// set to Recreate or RollingUpdate via unsupportedConfigOverrides.operator
+// (deployment.strategy). By default, single-node clusters use Recreate as there
+// is no other node to move the oauth-server to during a rolling update.
+func setDeploymentStrategy(spec *appsv1.DeploymentSpec, infrastructureConfig *configv1.Infrastructure, unsupportedConfig map[string]interface{}) error {
+	fieldName := strings.Join(deploymentStrategyPath, ".")
+
+	strategyType, found, err := unstructured.NestedString(unsupportedConfig, deploymentStrategyPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", fieldName, err)
+	}
+	if !found || len(strategyType) == 0 {
+		if infrastructureConfig.Status.ControlPlaneTopology != configv1.SingleReplicaTopologyMode {
+			return nil
+		}
+		strategyType = string(appsv1.RecreateDeploymentStrategyType)
+	}
+
+	switch appsv1.DeploymentStrategyType(strategyType) {
+	case appsv1.RollingUpdateDeploymentStrategyType:
+		// the rolling update parameters come with the deployment asset
+	case appsv1.RecreateDeploymentStrategyType:
+		spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	default:
+		return fmt.Errorf("%s must be one of %q or %q, got %q", fieldName, appsv1.RecreateDeploymentStrategyType, appsv1.RollingUpdateDeploymentStrategyType, strategyType)
+	}
+
+	return nil
+}
+
+// setStartupStagger paces the rollout of the oauth-server pods so that they do
+// not all hit the kube-apiserver with their initial lists at the same time. A
+// new pod has to be ready for deployment.startupStaggerSeconds of
+// unsupportedConfigOverrides.operator before it counts as available, and only then the
+// rolling update moves on to the next one. The Recreate strategy starts all
+// pods at once regardless.
+func setStartupStagger(spec *appsv1.DeploymentSpec, unsupportedConfig map[string]interface{}) error {
+	fieldName := strings.Join(startupStaggerPath, ".")
+
+	seconds, found, err := unstructured.NestedFloat64(unsupportedConfig, startupStaggerPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", fieldName, err)
+	}
+	if !found {
+		return nil
+	}
+	if seconds != float64(int32(seconds)) || seconds < 0 || seconds > 600 {
+		return fmt.Errorf("%s must be an integer in the range 0-600, got %v", fieldName, seconds)
+	}
+
+	spec.MinReadySeconds = int32(seconds)
+	return nil
+}
+
+// restartOnContentChangeResources returns the resources listed in
+// deployment.restartOnContentChange of unsupportedConfigOverrides.operator, e.g.
+// "secrets/v4-0-config-user-idp-0-file-data". The oauth-server is restarted
+// when the content of these resources changes rather than their resource
+// version, through a dedicated annotation decoupled from the rvs-hash.
+func restartOnContentChangeResources(unsupportedConfig map[string]interface{}) (sets.String, error) {
+	fieldName := strings.Join(restartOnContentChangePath, ".")
+
+	resources, _, err := unstructured.NestedStringSlice(unsupportedConfig, restartOnContentChangePath...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", fieldName, err)
+	}
+
+	ret := sets.NewString()
+	for _, resource := range resources {
+		kind, name, found := strings.Cut(resource, "/")
+		if !found || (kind != "configmaps" && kind != "secrets") || len(name) == 0 {
+			return nil, fmt.Errorf("%s: expected \"configmaps/<name>\" or \"secrets/<name>\", got %q", fieldName, resource)
+		}
+		ret.Insert(resource)
+	}
+	return ret, nil
+}
+
+// setLogForwardingAnnotations annotates the oauth-server pods for log collectors
+// when audit.logForwarding.enabled is set in unsupportedConfigOverrides.operator. The
+// path of the audit log is annotated by default, additional annotations can be
+// set via audit.logForwarding.annotations but do not replace the ones managed
+// by the operator. Forwarding requires the audit log to be written to the host.
+func setLogForwardingAnnotations(podTemplate *corev1.PodTemplateSpec, unsupportedConfig map[string]interface{}, args arguments.ServerArguments) error {
+	enabled, _, err := unstructured.NestedBool(unsupportedConfig, logForwardingEnabledPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(logForwardingEnabledPath, "."), err)
+	}
+	if !enabled {
+		return nil
+	}
+
+	annotations, _, err := unstructured.NestedStringMap(unsupportedConfig, logForwardingAnnotationsPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(logForwardingAnnotationsPath, "."), err)
+	}
+
+	podMeta := &podTemplate.ObjectMeta
+	if podMeta.Annotations == nil {
+		podMeta.Annotations = map[string]string{}
+	}
+	// annotations managed by the operator cannot be overridden
+	for k, v := range annotations {
+		if _, managed := podMeta.Annotations[k]; !managed {
+			podMeta.Annotations[k] = v
+		}
+	}
+	// the audit log path is only known while auditing is enabled
+	if auditLogPath := args["audit-log-path"]; len(auditLogPath) == 1 && auditLogPath[0] != "-" {
+		hostPath, ok := auditLogHostPath(&podTemplate.Spec, auditLogPath[0])
+		if !ok {
+			return fmt.Errorf("audit log forwarding requires the audit log %q to be written to the host", auditLogPath[0])
+		}
+		podMeta.Annotations[auditLogPathAnnotation] = hostPath
+	}
+
+	return nil
+}
+
+// setSidecarInjectionAnnotations opts the oauth-server pods out of service mesh
+// sidecar injection. The injection annotations can be changed or added to via
+// deployment.sidecarInjectionAnnotations in unsupportedConfigOverrides.operator, where
+// an empty value removes a default annotation. Other annotations managed by the
+// operator cannot be overridden.
+func setSidecarInjectionAnnotations(podMeta *metav1.ObjectMeta, unsupportedConfig map[string]interface{}) error {
+	annotations, _, err := unstructured.NestedStringMap(unsupportedConfig, sidecarInjectionPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(sidecarInjectionPath, "."), err)
+	}
+
+	if podMeta.Annotations == nil {
+		podMeta.Annotations = map[string]string{}
+	}
+	for k, v := range defaultSidecarInjectionAnnotations {
+		if _, managed := podMeta.Annotations[k]; !managed {
+			podMeta.Annotations[k] = v
+		}
+	}
+	for k, v := range annotations {
+		if _, isDefault := defaultSidecarInjectionAnnotations[k]; !isDefault {
+			if _, managed := podMeta.Annotations[k]; managed {
+				continue
+			}
+		}
+		if len(v) == 0 {
+			delete(podMeta.Annotations, k)
+			continue
+		}
+		podMeta.Annotations[k] = v
+	}
+
+	return nil
+}
+
+// applyDefaultNodeSelector decides on the cluster-wide default node selector
+// (schedulers.config.openshift.io/cluster spec.defaultNodeSelector) for the
+// oauth-server pods. By default it is overridden: the namespace opts out of it
+// and the pods are placed on the control plane only. When
+// deployment.honorDefaultNodeSelector is set in unsupportedConfigOverrides.operator, the
+// default node selector is merged into the pod's node selector, with the
+// control-plane placement taking precedence on conflicting keys.
+func applyDefaultNodeSelector(podSpec *corev1.PodSpec, schedulerConfig *configv1.Scheduler, unsupportedConfig map[string]interface{}) error {
+	honor, _, err := unstructured.NestedBool(unsupportedConfig, honorDefaultNodeSelectorPath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(honorDefaultNodeSelectorPath, "."), err)
+	}
+	if !honor || schedulerConfig == nil || len(schedulerConfig.Spec.DefaultNodeSelector) == 0 {
+		return nil
+	}
+
+	defaultNodeSelector, err := labels.ConvertSelectorToLabelsMap(schedulerConfig.Spec.DefaultNodeSelector)
+	if err != nil {
+		return fmt.Errorf("unable to parse the default node selector %q: %w", schedulerConfig.Spec.DefaultNodeSelector, err)
+	}
+
+	if podSpec.NodeSelector == nil {
+		podSpec.NodeSelector = map[string]string{}
+	}
+	for k, v := range defaultNodeSelector {
+		if _, ok := podSpec.NodeSelector[k]; ok {
+			continue
+		}
+		podSpec.NodeSelector[k] = v
+	}
+
+	return nil
+}
+
+// oauthServerContainer returns the oauth-server container of the given pod spec
+func oauthServerContainer(podSpec *corev1.PodSpec) (*corev1.Container, error) {
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == oauthServerContainerName {
+			return &podSpec.Containers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find the %q container in the pod spec", oauthServerContainerName)
+}
+
+func getSyncDataFromOperatorConfig(observedConfig []byte, unsupportedConfig map[string]interface{}) (*datasync.ConfigSyncData, error) {
 	var configDeserialized map[string]interface{}
 	if err := yaml.Unmarshal(observedConfig, &configDeserialized); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal the observedConfig: %v", err)
 	}
 
-	return observeoauth.GetIDPConfigSyncData(configDeserialized)
+	return observeoauth.GetIDPConfigSyncData(configDeserialized, unsupportedConfig)
 }
 
 // TODO: reuse the library-go helper for this
@@ -136,7 +848,7 @@ func getLogLevel(logLevel operatorv1.LogLevel) int {
 	case operatorv1.Trace:
 		return 6
 	case operatorv1.TraceAll:
-		return 100 // this is supposed to be 8 but I prefer "all" to really mean all
+		return 8 // klog does not log anything beyond 8, higher levels only add noise
 	default:
 		return 0
 	}
@@ -151,6 +863,49 @@ func proxyConfigToEnvVars(proxy *configv1.Proxy) []corev1.EnvVar {
 	return envVars
 }
 
+// validateProxyEnv records a warning for every inconsistency of the proxy env
+// vars returned by proxyConfigToEnvVars: an HTTPS proxy without an HTTP proxy,
+// and NO_PROXY entries that match the host of a proxy, which would make the
+// oauth-server connect to the proxy itself without going through it.
+func validateProxyEnv(envVars []corev1.EnvVar, warnings *common.ConfigWarnings) {
+	env := map[string]string{}
+	for _, envVar := range envVars {
+		env[envVar.Name] = envVar.Value
+	}
+
+	if len(env["HTTPS_PROXY"]) > 0 && len(env["HTTP_PROXY"]) == 0 {
+		warnings.Warnf("HTTPS_PROXY is set to %q but HTTP_PROXY is empty", env["HTTPS_PROXY"])
+	}
+
+	if len(env["NO_PROXY"]) == 0 {
+		return
+	}
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY"} {
+		if len(env[name]) == 0 {
+			continue
+		}
+		proxyURL, err := url.Parse(env[name])
+		if err != nil || len(proxyURL.Hostname()) == 0 {
+			warnings.Warnf("%s %q is not a valid URL", name, env[name])
+			continue
+		}
+		proxyHost := strings.ToLower(proxyURL.Hostname())
+
+		for _, entry := range strings.Split(env["NO_PROXY"], ",") {
+			entry = strings.ToLower(strings.TrimSpace(entry))
+			if host, _, err := net.SplitHostPort(entry); err == nil {
+				entry = host
+			}
+			if len(entry) == 0 {
+				continue
+			}
+			if entry == proxyHost || (strings.HasPrefix(entry, ".") && strings.HasSuffix(proxyHost, entry)) {
+				warnings.Warnf("NO_PROXY entry %q matches the host of %s %q", entry, name, env[name])
+			}
+		}
+	}
+}
+
 func appendEnvVar(envVars []corev1.EnvVar, envName, envVal string) []corev1.EnvVar {
 	if len(envVal) > 0 {
 		return append(envVars, corev1.EnvVar{Name: envName, Value: envVal})
@@ -0,0 +1,1173 @@
+package deployment
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	operatorv1listers "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+
+	"github.com/openshift/cluster-authentication-operator/bindata"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common/arguments"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
+	observeoauth "github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/oauth"
+)
+
+const testObservedConfig = `
+{
+  "oauthServer": {
+    "serverArguments": {
+      "audit-log-format": ["json"],
+      "audit-log-maxbackup": ["10"],
+      "audit-log-maxsize": ["100"],
+      "audit-log-path": ["/var/log/oauth-server/audit.log"],
+      "audit-policy-file": ["/var/run/configmaps/audit/audit.yaml"]
+    }
+  }
+}
+`
+
+func newTestOperatorConfig(unsupportedConfig string) *operatorv1.Authentication {
+	operatorConfig := &operatorv1.Authentication{}
+	operatorConfig.Spec.ObservedConfig = runtime.RawExtension{Raw: []byte(testObservedConfig)}
+	if len(unsupportedConfig) > 0 {
+		operatorConfig.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(unsupportedConfig)}
+	}
+	return operatorConfig
+}
+
+func TestAuditLogComplianceMode(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		expectRestricted  bool
+	}{
+		{
+			name: "compliance mode off by default",
+		},
+		{
+			name:              "compliance mode on",
+			unsupportedConfig: `{"operator":{"audit":{"complianceMode":true}}}`,
+			expectRestricted:  true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(
+				newTestOperatorConfig(tt.unsupportedConfig),
+				&configv1.Proxy{},
+				&configv1.Scheduler{},
+				&configv1.Infrastructure{},
+				false,
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			container, err := oauthServerContainer(&deployment.Spec.Template.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			script := container.Args[0]
+			restricted := strings.Contains(script, "umask 0077\nchmod -R go-rwx /var/log/oauth-server\nexec oauth-server")
+			if restricted != tt.expectRestricted {
+				t.Errorf("expected restricted audit log permissions to be %t, got script:\n%s", tt.expectRestricted, script)
+			}
+		})
+	}
+}
+
+func TestOAuthServerContainer(t *testing.T) {
+	deployment, err := getOAuthServerDeployment(newTestOperatorConfig(""), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rendered := deployment.Spec.Template.Spec.Containers
+	if len(rendered) != 1 || rendered[0].Name != oauthServerContainerName {
+		t.Fatalf("expected a single container named %q, got %v", oauthServerContainerName, rendered)
+	}
+
+	// the lookup must not depend on the position of the container
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "sidecar"},
+			{Name: oauthServerContainerName},
+		},
+	}
+	container, err := oauthServerContainer(podSpec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if container != &podSpec.Containers[1] {
+		t.Errorf("expected the %q container, got %q", oauthServerContainerName, container.Name)
+	}
+
+	if _, err := oauthServerContainer(&corev1.PodSpec{Containers: []corev1.Container{{Name: "sidecar"}}}); err == nil {
+		t.Errorf("expected an error when the %q container is missing", oauthServerContainerName)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	for _, tt := range []struct {
+		logLevel    operatorv1.LogLevel
+		expectedArg string
+	}{
+		{logLevel: "", expectedArg: "--v=2"},
+		{logLevel: operatorv1.Normal, expectedArg: "--v=2"},
+		{logLevel: operatorv1.Debug, expectedArg: "--v=4"},
+		{logLevel: operatorv1.Trace, expectedArg: "--v=6"},
+		{logLevel: operatorv1.TraceAll, expectedArg: "--v=8"},
+	} {
+		t.Run(string(tt.logLevel), func(t *testing.T) {
+			operatorConfig := newTestOperatorConfig("")
+			operatorConfig.Spec.LogLevel = tt.logLevel
+
+			deployment, err := getOAuthServerDeployment(operatorConfig, &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false, "secrets:a:1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			container, err := oauthServerContainer(&deployment.Spec.Template.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(container.Args[0], tt.expectedArg+" ") {
+				t.Errorf("expected the server arguments to contain %q, got %q", tt.expectedArg, container.Args[0])
+			}
+
+			// log levels that map to the same verbosity must not cause a rollout
+			annotation := deployment.Spec.Template.Annotations[logLevelAnnotation]
+			if expected := strings.TrimPrefix(tt.expectedArg, "--v="); annotation != expected {
+				t.Errorf("expected the %s annotation to be %q, got %q", logLevelAnnotation, expected, annotation)
+			}
+
+			// the log level is not tracked in the rvs-hash
+			if got, expected := deployment.Spec.Template.Annotations["operator.openshift.io/rvs-hash"], resourceVersionsHash("secrets:a:1"); got != expected {
+				t.Errorf("expected the rvs-hash to only depend on the resource versions, got %q", got)
+			}
+		})
+	}
+}
+
+func TestResourceVersionsHash(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		logLevel         operatorv1.LogLevel
+		resourceVersions []string
+	}{
+		{
+			name: "no resource versions",
+		},
+		{
+			name:             "single resource version",
+			resourceVersions: []string{"configmaps:audit:10"},
+		},
+		{
+			name:             "unsorted resource versions",
+			resourceVersions: []string{"secrets:b:2", "configmaps:a:1", "secrets:a:3"},
+		},
+		{
+			name:             "log level",
+			logLevel:         operatorv1.Debug,
+			resourceVersions: []string{"configmaps:audit:10"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			passed := append([]string{}, tt.resourceVersions...)
+			hash := resourceVersionsHash(passed...)
+
+			if !reflect.DeepEqual(passed, append([]string{}, tt.resourceVersions...)) {
+				t.Errorf("resource versions were modified: %v", passed)
+			}
+
+			operatorConfig := newTestOperatorConfig("")
+			operatorConfig.Spec.LogLevel = tt.logLevel
+
+			deployment, err := getOAuthServerDeployment(operatorConfig, &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false, tt.resourceVersions...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := deployment.Annotations["operator.openshift.io/rvs-hash"]; got != hash {
+				t.Errorf("expected deployment rvs-hash %q, got %q", hash, got)
+			}
+			if got := deployment.Spec.Template.Annotations["operator.openshift.io/rvs-hash"]; got != hash {
+				t.Errorf("expected pod template rvs-hash %q, got %q", hash, got)
+			}
+		})
+	}
+
+	if resourceVersionsHash("a:1", "b:2") != resourceVersionsHash("b:2", "a:1") {
+		t.Errorf("expected the hash to not depend on the order of resource versions")
+	}
+	if resourceVersionsHash("a:1") == resourceVersionsHash("a:2") {
+		t.Errorf("expected the hash to change with the resource versions")
+	}
+}
+
+// TestMetricsPort makes sure the port scraped by the oauth-openshift
+// ServiceMonitor shipped in the manifests is declared by the oauth-server.
+func TestMetricsPort(t *testing.T) {
+	deployment, err := getOAuthServerDeployment(newTestOperatorConfig(""), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	container, err := oauthServerContainer(&deployment.Spec.Template.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, port := range container.Ports {
+		if port.Name == "https" && port.ContainerPort == 6443 && port.Protocol == corev1.ProtocolTCP {
+			return
+		}
+	}
+	t.Errorf("expected the https metrics port to be declared, got %v", container.Ports)
+}
+
+func TestGuaranteedQoS(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		expectGuaranteed  bool
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:              "guaranteed QoS",
+			unsupportedConfig: `{"operator":{"deployment":{"guaranteedQoS":true}}}`,
+			expectGuaranteed:  true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if guaranteed := isGuaranteedQoS(&deployment.Spec.Template.Spec); guaranteed != tt.expectGuaranteed {
+				t.Errorf("expected Guaranteed QoS to be %t, got %t", tt.expectGuaranteed, guaranteed)
+			}
+		})
+	}
+}
+
+// isGuaranteedQoS follows the rules of the kubelet for the Guaranteed QoS class:
+// every container sets CPU and memory limits that are equal to its requests
+func isGuaranteedQoS(podSpec *corev1.PodSpec) bool {
+	for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			limit, ok := container.Resources.Limits[name]
+			if !ok || limit.IsZero() {
+				return false
+			}
+			if request, ok := container.Resources.Requests[name]; ok && request.Cmp(limit) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestPreferredAntiAffinity(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		expectedWeight    int32
+		expectErr         bool
+	}{
+		{
+			name:           "default",
+			expectedWeight: 100,
+		},
+		{
+			name:              "custom weight",
+			unsupportedConfig: `{"operator":{"deployment":{"antiAffinityWeight":20}}}`,
+			expectedWeight:    20,
+		},
+		{
+			name:              "weight out of range",
+			unsupportedConfig: `{"operator":{"deployment":{"antiAffinityWeight":101}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "fractional weight",
+			unsupportedConfig: `{"operator":{"deployment":{"antiAffinityWeight":2.5}}}`,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			affinity := deployment.Spec.Template.Spec.Affinity
+			if affinity == nil || affinity.PodAntiAffinity == nil {
+				t.Fatal("expected a pod anti-affinity")
+			}
+
+			for _, term := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				if term.PodAffinityTerm.TopologyKey != corev1.LabelHostname {
+					continue
+				}
+				if term.Weight != tt.expectedWeight {
+					t.Errorf("expected weight %d, got %d", tt.expectedWeight, term.Weight)
+				}
+				if term.PodAffinityTerm.LabelSelector.MatchLabels["app"] != "oauth-openshift" {
+					t.Errorf("expected the term to select the oauth-server pods, got %v", term.PodAffinityTerm.LabelSelector)
+				}
+				return
+			}
+			t.Errorf("expected a preferred anti-affinity term on %s", corev1.LabelHostname)
+		})
+	}
+}
+
+func TestDefaultNodeSelector(t *testing.T) {
+	schedulerConfig := &configv1.Scheduler{
+		Spec: configv1.SchedulerSpec{
+			DefaultNodeSelector: "type=user-node,node-role.kubernetes.io/master=no",
+		},
+	}
+
+	for _, tt := range []struct {
+		name                 string
+		unsupportedConfig    string
+		expectedNodeSelector map[string]string
+	}{
+		{
+			name: "overridden by default",
+			expectedNodeSelector: map[string]string{
+				"node-role.kubernetes.io/master": "",
+			},
+		},
+		{
+			name:              "honored",
+			unsupportedConfig: `{"operator":{"deployment":{"honorDefaultNodeSelector":true}}}`,
+			expectedNodeSelector: map[string]string{
+				"node-role.kubernetes.io/master": "",
+				"type":                           "user-node",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, schedulerConfig, &configv1.Infrastructure{}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tt.expectedNodeSelector, deployment.Spec.Template.Spec.NodeSelector) {
+				t.Errorf("expected node selector %v, got %v", tt.expectedNodeSelector, deployment.Spec.Template.Spec.NodeSelector)
+			}
+		})
+	}
+}
+
+func TestLogForwardingAnnotations(t *testing.T) {
+	for _, tt := range []struct {
+		name                string
+		unsupportedConfig   string
+		expectedAnnotations map[string]string
+		unexpected          []string
+	}{
+		{
+			name:       "disabled",
+			unexpected: []string{"operator.openshift.io/audit-log-path"},
+		},
+		{
+			name:              "enabled",
+			unsupportedConfig: `{"operator":{"audit":{"logForwarding":{"enabled":true}}}}`,
+			expectedAnnotations: map[string]string{
+				"operator.openshift.io/audit-log-path": "/var/log/oauth-server/audit.log",
+			},
+		},
+		{
+			name:              "enabled with custom annotations",
+			unsupportedConfig: `{"operator":{"audit":{"logForwarding":{"enabled":true,"annotations":{"collector.example.com/pipeline":"audit","operator.openshift.io/rvs-hash":"overridden"}}}}}`,
+			expectedAnnotations: map[string]string{
+				"operator.openshift.io/audit-log-path": "/var/log/oauth-server/audit.log",
+				"collector.example.com/pipeline":       "audit",
+			},
+		},
+		{
+			name:              "custom annotations without enabling",
+			unsupportedConfig: `{"operator":{"audit":{"logForwarding":{"annotations":{"collector.example.com/pipeline":"audit"}}}}}`,
+			unexpected:        []string{"operator.openshift.io/audit-log-path", "collector.example.com/pipeline"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false, "secrets:a:1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			annotations := deployment.Spec.Template.Annotations
+			for k, v := range tt.expectedAnnotations {
+				if annotations[k] != v {
+					t.Errorf("expected annotation %s=%q, got %q", k, v, annotations[k])
+				}
+			}
+			for _, k := range tt.unexpected {
+				if _, ok := annotations[k]; ok {
+					t.Errorf("unexpected annotation %s", k)
+				}
+			}
+			if got, expected := annotations["operator.openshift.io/rvs-hash"], resourceVersionsHash("secrets:a:1"); got != expected {
+				t.Errorf("expected the rvs-hash annotation to be kept, got %q", got)
+			}
+		})
+	}
+}
+
+func TestEphemeralStorage(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		expectedRequest   string
+		expectedLimit     string
+		expectErr         bool
+	}{
+		{
+			name:            "default",
+			expectedRequest: "50Mi",
+		},
+		{
+			name:              "custom request and limit",
+			unsupportedConfig: `{"operator":{"deployment":{"ephemeralStorage":{"request":"100Mi","limit":"1Gi"}}}}`,
+			expectedRequest:   "100Mi",
+			expectedLimit:     "1Gi",
+		},
+		{
+			name:              "emptyDir audit log volume",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"type":"EmptyDir"}}}}`,
+			expectedRequest:   "1150Mi",
+		},
+		{
+			name:              "emptyDir audit log volume with a custom request and limit",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"type":"EmptyDir"}},"deployment":{"ephemeralStorage":{"request":"100Mi","limit":"2Gi"}}}}`,
+			expectedRequest:   "1200Mi",
+			expectedLimit:     "2Gi",
+		},
+		{
+			name:              "emptyDir audit log volume above the limit",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"type":"EmptyDir"}},"deployment":{"ephemeralStorage":{"limit":"1Gi"}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "limit lower than the default request",
+			unsupportedConfig: `{"operator":{"deployment":{"ephemeralStorage":{"limit":"10Mi"}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "invalid quantity",
+			unsupportedConfig: `{"operator":{"deployment":{"ephemeralStorage":{"request":"lots"}}}}`,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			container, err := oauthServerContainer(&deployment.Spec.Template.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request, ok := container.Resources.Requests[corev1.ResourceEphemeralStorage]
+			if !ok || request.String() != tt.expectedRequest {
+				t.Errorf("expected ephemeral-storage request %q, got %q", tt.expectedRequest, request.String())
+			}
+
+			limit, ok := container.Resources.Limits[corev1.ResourceEphemeralStorage]
+			if len(tt.expectedLimit) == 0 {
+				if ok {
+					t.Errorf("expected no ephemeral-storage limit, got %q", limit.String())
+				}
+			} else if !ok || limit.String() != tt.expectedLimit {
+				t.Errorf("expected ephemeral-storage limit %q, got %q", tt.expectedLimit, limit.String())
+			}
+		})
+	}
+}
+
+func TestDeploymentStrategy(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		topology          configv1.TopologyMode
+		unsupportedConfig string
+		expectedType      appsv1.DeploymentStrategyType
+		expectErr         bool
+	}{
+		{
+			name:         "rolling update on HA",
+			topology:     configv1.HighlyAvailableTopologyMode,
+			expectedType: appsv1.RollingUpdateDeploymentStrategyType,
+		},
+		{
+			name:         "recreate on SNO",
+			topology:     configv1.SingleReplicaTopologyMode,
+			expectedType: appsv1.RecreateDeploymentStrategyType,
+		},
+		{
+			name:              "recreate on HA when configured",
+			topology:          configv1.HighlyAvailableTopologyMode,
+			unsupportedConfig: `{"operator":{"deployment":{"strategy":"Recreate"}}}`,
+			expectedType:      appsv1.RecreateDeploymentStrategyType,
+		},
+		{
+			name:              "rolling update on SNO when configured",
+			topology:          configv1.SingleReplicaTopologyMode,
+			unsupportedConfig: `{"operator":{"deployment":{"strategy":"RollingUpdate"}}}`,
+			expectedType:      appsv1.RollingUpdateDeploymentStrategyType,
+		},
+		{
+			name:              "unknown strategy",
+			unsupportedConfig: `{"operator":{"deployment":{"strategy":"BlueGreen"}}}`,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			infrastructureConfig := &configv1.Infrastructure{}
+			infrastructureConfig.Status.ControlPlaneTopology = tt.topology
+
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, &configv1.Scheduler{}, infrastructureConfig, false)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			strategy := deployment.Spec.Strategy
+			if strategy.Type != tt.expectedType {
+				t.Errorf("expected strategy %q, got %q", tt.expectedType, strategy.Type)
+			}
+			switch strategy.Type {
+			case appsv1.RollingUpdateDeploymentStrategyType:
+				if strategy.RollingUpdate == nil {
+					t.Error("expected rolling update parameters to be set")
+				}
+			case appsv1.RecreateDeploymentStrategyType:
+				if strategy.RollingUpdate != nil {
+					t.Errorf("expected no rolling update parameters with the Recreate strategy, got %v", strategy.RollingUpdate)
+				}
+			}
+		})
+	}
+}
+
+func TestSidecarInjectionAnnotations(t *testing.T) {
+	for _, tt := range []struct {
+		name                string
+		unsupportedConfig   string
+		expectedAnnotations map[string]string
+		unexpected          []string
+	}{
+		{
+			name: "opted out by default",
+			expectedAnnotations: map[string]string{
+				"sidecar.istio.io/inject": "false",
+				"linkerd.io/inject":       "disabled",
+			},
+		},
+		{
+			name:              "overridden",
+			unsupportedConfig: `{"operator":{"deployment":{"sidecarInjectionAnnotations":{"sidecar.istio.io/inject":"true","linkerd.io/inject":"","kuma.io/sidecar-injection":"disabled"}}}}`,
+			expectedAnnotations: map[string]string{
+				"sidecar.istio.io/inject":   "true",
+				"kuma.io/sidecar-injection": "disabled",
+			},
+			unexpected: []string{"linkerd.io/inject"},
+		},
+		{
+			name:              "managed annotations are kept",
+			unsupportedConfig: `{"operator":{"deployment":{"sidecarInjectionAnnotations":{"operator.openshift.io/rvs-hash":""}}}}`,
+			expectedAnnotations: map[string]string{
+				"operator.openshift.io/rvs-hash": resourceVersionsHash(),
+				"sidecar.istio.io/inject":        "false",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			annotations := deployment.Spec.Template.Annotations
+			for k, v := range tt.expectedAnnotations {
+				if annotations[k] != v {
+					t.Errorf("expected annotation %s=%q, got %q", k, v, annotations[k])
+				}
+			}
+			for _, k := range tt.unexpected {
+				if _, ok := annotations[k]; ok {
+					t.Errorf("unexpected annotation %s", k)
+				}
+			}
+		})
+	}
+}
+
+// TestAuditPolicyFileMatchesConfigMap makes sure the audit policy file passed to
+// the oauth-server is exactly the audit policy key of the mounted audit configmap
+func TestAuditPolicyFileMatchesConfigMap(t *testing.T) {
+	deployment, err := getOAuthServerDeployment(newTestOperatorConfig(""), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	container, err := oauthServerContainer(&deployment.Spec.Template.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mountPath string
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == "audit-policies" {
+			mountPath = mount.MountPath
+		}
+	}
+	if len(mountPath) == 0 {
+		t.Fatal("expected the audit configmap to be mounted")
+	}
+
+	auditConfigMap := resourceread.ReadConfigMapV1OrDie(bindata.MustAsset("oauth-openshift/audit-policy.yaml"))
+	if _, ok := auditConfigMap.Data[observeoauth.AuditPolicyKey]; !ok {
+		t.Fatalf("expected the audit configmap to contain the %q key", observeoauth.AuditPolicyKey)
+	}
+
+	expected := "--audit-policy-file=" + path.Join(mountPath, observeoauth.AuditPolicyKey)
+	if !strings.Contains(container.Args[0], expected) {
+		t.Errorf("expected the server arguments to contain %q, got %q", expected, container.Args[0])
+	}
+}
+
+func TestAuditLogVolume(t *testing.T) {
+	for _, tt := range []struct {
+		name                  string
+		unsupportedConfig     string
+		expectedVolume        corev1.VolumeSource
+		expectedLogPathOnHost string
+		expectErr             bool
+	}{
+		{
+			name:           "default",
+			expectedVolume: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/log/oauth-server"}},
+		},
+		{
+			name:                  "custom host path",
+			unsupportedConfig:     `{"operator":{"audit":{"logVolume":{"type":"HostPath","hostPath":"/var/log/collected/oauth-server"},"logForwarding":{"enabled":true}}}}`,
+			expectedVolume:        corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/log/collected/oauth-server"}},
+			expectedLogPathOnHost: "/var/log/collected/oauth-server/audit.log",
+		},
+		{
+			name:                  "host path without the type",
+			unsupportedConfig:     `{"operator":{"audit":{"logVolume":{"hostPath":"/var/log/audit-oauth"},"logForwarding":{"enabled":true}}}}`,
+			expectedVolume:        corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/log/audit-oauth"}},
+			expectedLogPathOnHost: "/var/log/audit-oauth/audit.log",
+		},
+		{
+			name:              "emptyDir",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"type":"EmptyDir"}}}}`,
+			expectedVolume:    corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: resource.NewQuantity(1100*1024*1024, resource.BinarySI)}},
+		},
+		{
+			name:              "emptyDir with a host path",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"type":"EmptyDir","hostPath":"/var/log/oauth"}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "emptyDir with log forwarding",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"type":"EmptyDir"},"logForwarding":{"enabled":true}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "unknown type",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"type":"PersistentVolumeClaim"}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "relative host path",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"hostPath":"var/log/oauth"}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "host path escaping /var/log",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"hostPath":"/var/log/../../etc"}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "host path outside of /var/log",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"hostPath":"/etc/kubernetes"}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "/var/log itself",
+			unsupportedConfig: `{"operator":{"audit":{"logVolume":{"hostPath":"/var/log"}}}}`,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			var volume *corev1.Volume
+			for i := range deployment.Spec.Template.Spec.Volumes {
+				if deployment.Spec.Template.Spec.Volumes[i].Name == "audit-dir" {
+					volume = &deployment.Spec.Template.Spec.Volumes[i]
+				}
+			}
+			if volume == nil {
+				t.Fatal("expected an audit-dir volume")
+			}
+			if !reflect.DeepEqual(tt.expectedVolume, volume.VolumeSource) {
+				t.Errorf("expected audit-dir volume %#v, got %#v", tt.expectedVolume, volume.VolumeSource)
+			}
+
+			if got := deployment.Spec.Template.Annotations["operator.openshift.io/audit-log-path"]; got != tt.expectedLogPathOnHost {
+				t.Errorf("expected the audit log on the host at %q, got %q", tt.expectedLogPathOnHost, got)
+			}
+		})
+	}
+}
+
+func TestAuditLogSizeLimit(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		args          arguments.ServerArguments
+		expectedLimit string
+		expectErr     bool
+	}{
+		{
+			name:          "rotated logs",
+			args:          arguments.ServerArguments{"audit-log-maxsize": {"100"}, "audit-log-maxbackup": {"10"}},
+			expectedLimit: "1100Mi",
+		},
+		{
+			name:      "no rotation",
+			args:      arguments.ServerArguments{"audit-log-maxsize": {"0"}, "audit-log-maxbackup": {"10"}},
+			expectErr: true,
+		},
+		{
+			name:      "unlimited backups",
+			args:      arguments.ServerArguments{"audit-log-maxsize": {"100"}, "audit-log-maxbackup": {"0"}},
+			expectErr: true,
+		},
+		{
+			name:      "missing max size",
+			args:      arguments.ServerArguments{"audit-log-maxbackup": {"10"}},
+			expectErr: true,
+		},
+		{
+			name:      "not a number",
+			args:      arguments.ServerArguments{"audit-log-maxsize": {"100M"}, "audit-log-maxbackup": {"10"}},
+			expectErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, err := auditLogSizeLimit(tt.args)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err == nil && limit.String() != tt.expectedLimit {
+				t.Errorf("expected size limit %q, got %q", tt.expectedLimit, limit.String())
+			}
+		})
+	}
+}
+
+func TestAuditPolicyMountPath(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		expectedMountPath string
+	}{
+		{
+			name:              "default",
+			expectedMountPath: "/var/run/configmaps/audit",
+		},
+		{
+			name:              "override",
+			unsupportedConfig: `{"operator":{"audit":{"policyMountPath":"/etc/oauth-server/audit"}}}`,
+			expectedMountPath: "/etc/oauth-server/audit",
+		},
+		{
+			name:              "override of an unmanaged configmap",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false,"policyMountPath":"/etc/oauth-server/audit/"}}}`,
+			expectedMountPath: "/etc/oauth-server/audit",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := renderObservedAudit(t, tt.unsupportedConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			container, err := oauthServerContainer(&deployment.Spec.Template.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var mountPath string
+			for _, mount := range container.VolumeMounts {
+				if mount.Name == "audit-policies" {
+					mountPath = mount.MountPath
+				}
+			}
+			if mountPath != tt.expectedMountPath {
+				t.Errorf("expected the audit configmap to be mounted at %q, got %q", tt.expectedMountPath, mountPath)
+			}
+
+			expectedArg := "--audit-policy-file=" + path.Join(tt.expectedMountPath, "audit.yaml")
+			if !strings.Contains(container.Args[0], expectedArg) {
+				t.Errorf("expected the server arguments to contain %q, got %q", expectedArg, container.Args[0])
+			}
+		})
+	}
+}
+
+func TestAuditPolicyConfigMapOptional(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		expectedOptional  bool
+	}{
+		{
+			name: "managed",
+		},
+		{
+			name:              "unmanaged",
+			unsupportedConfig: `{"operator":{"audit":{"manageConfigMap":false,"policyFile":"/etc/audit/policy.yaml"}}}`,
+			expectedOptional:  true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := renderObservedAudit(t, tt.unsupportedConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var volume *corev1.Volume
+			for i := range deployment.Spec.Template.Spec.Volumes {
+				if deployment.Spec.Template.Spec.Volumes[i].Name == "audit-policies" {
+					volume = &deployment.Spec.Template.Spec.Volumes[i]
+				}
+			}
+			if volume == nil || volume.ConfigMap == nil {
+				t.Fatal("expected an audit-policies configmap volume")
+			}
+			if optional := volume.ConfigMap.Optional != nil && *volume.ConfigMap.Optional; optional != tt.expectedOptional {
+				t.Errorf("expected the audit configmap to be optional: %t, got %t", tt.expectedOptional, optional)
+			}
+		})
+	}
+}
+
+func TestUserManagedAuditPolicyFile(t *testing.T) {
+	for _, tt := range []struct {
+		name               string
+		unsupportedConfig  string
+		expectedPolicyFile string
+	}{
+		{
+			name:               "key of the audit configmap other than the managed one",
+			unsupportedConfig:  `{"operator":{"audit":{"manageConfigMap":false,"policyFile":"/var/run/configmaps/audit/external.yaml"}}}`,
+			expectedPolicyFile: "/var/run/configmaps/audit/external.yaml",
+		},
+		{
+			name:               "policy file outside of the audit configmap mount",
+			unsupportedConfig:  `{"operator":{"audit":{"manageConfigMap":false,"policyFile":"/etc/audit/policy.yaml"}}}`,
+			expectedPolicyFile: "/etc/audit/policy.yaml",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := renderObservedAudit(t, tt.unsupportedConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			container, err := oauthServerContainer(&deployment.Spec.Template.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedArg := "--audit-policy-file=" + tt.expectedPolicyFile
+			if !strings.Contains(container.Args[0], expectedArg) {
+				t.Errorf("expected the server arguments to contain %q, got %q", expectedArg, container.Args[0])
+			}
+		})
+	}
+}
+
+func TestObservedAuditWebhookThrottle(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		expectedArgs      []string
+		expectErr         bool
+	}{
+		{
+			name:              "positive throttle",
+			unsupportedConfig: `{"operator":{"audit":{"webhook":{"configFile":"/var/run/configmaps/audit/webhook.kubeconfig","throttleQPS":2.5,"throttleBurst":15}}}}`,
+			expectedArgs: []string{
+				"--audit-webhook-config-file=/var/run/configmaps/audit/webhook.kubeconfig",
+				"--audit-webhook-batch-throttle-qps=2.5",
+				"--audit-webhook-batch-throttle-burst=15",
+			},
+		},
+		{
+			name:              "negative qps",
+			unsupportedConfig: `{"operator":{"audit":{"webhook":{"configFile":"/var/run/configmaps/audit/webhook.kubeconfig","throttleQPS":-1}}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "fractional burst",
+			unsupportedConfig: `{"operator":{"audit":{"webhook":{"configFile":"/var/run/configmaps/audit/webhook.kubeconfig","throttleBurst":1.5}}}}`,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := renderObservedAudit(t, tt.unsupportedConfig)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			container, err := oauthServerContainer(&deployment.Spec.Template.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, expectedArg := range tt.expectedArgs {
+				if !strings.Contains(container.Args[0], expectedArg) {
+					t.Errorf("expected the server arguments to contain %q, got %q", expectedArg, container.Args[0])
+				}
+			}
+		})
+	}
+}
+
+// renderObservedAudit observes the audit server arguments from the given
+// unsupportedConfigOverrides and renders the deployment from the same overrides
+func renderObservedAudit(t *testing.T, unsupportedConfig string) (*appsv1.Deployment, error) {
+	operatorConfig := newTestOperatorConfig(unsupportedConfig)
+	operatorConfig.Name = "cluster"
+
+	operatorIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := operatorIndexer.Add(operatorConfig); err != nil {
+		t.Fatal(err)
+	}
+	listers := configobservation.Listers{
+		APIServerLister_:             configv1listers.NewAPIServerLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		AuthenticationOperatorLister: operatorv1listers.NewAuthenticationLister(operatorIndexer),
+	}
+	observed, errs := observeoauth.ObserveAudit(listers, events.NewInMemoryRecorder(t.Name()), map[string]interface{}{})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	observedConfig, err := json.Marshal(map[string]interface{}{"oauthServer": observed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	operatorConfig.Spec.ObservedConfig = runtime.RawExtension{Raw: observedConfig}
+
+	return getOAuthServerDeployment(operatorConfig, &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+}
+
+func TestValidateProxyEnv(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		proxyStatus      configv1.ProxyStatus
+		expectedWarnings string
+	}{
+		{
+			name: "no proxy",
+		},
+		{
+			name: "consistent proxy",
+			proxyStatus: configv1.ProxyStatus{
+				HTTPProxy:  "http://proxy.example.com:3128",
+				HTTPSProxy: "http://proxy.example.com:3129",
+				NoProxy:    ".cluster.local,.svc,10.0.0.0/16,example.org",
+			},
+		},
+		{
+			name: "HTTP proxy only",
+			proxyStatus: configv1.ProxyStatus{
+				HTTPProxy: "http://proxy.example.com:3128",
+			},
+		},
+		{
+			name: "HTTPS proxy only",
+			proxyStatus: configv1.ProxyStatus{
+				HTTPSProxy: "http://proxy.example.com:3128",
+			},
+			expectedWarnings: `HTTPS_PROXY is set to "http://proxy.example.com:3128" but HTTP_PROXY is empty`,
+		},
+		{
+			name: "NO_PROXY lists the proxy host",
+			proxyStatus: configv1.ProxyStatus{
+				HTTPProxy:  "http://proxy.example.com:3128",
+				HTTPSProxy: "http://Proxy.example.com:3128",
+				NoProxy:    ".svc, proxy.example.com:3128",
+			},
+			expectedWarnings: `NO_PROXY entry "proxy.example.com" matches the host of HTTPS_PROXY "http://Proxy.example.com:3128"` + "\n" +
+				`NO_PROXY entry "proxy.example.com" matches the host of HTTP_PROXY "http://proxy.example.com:3128"`,
+		},
+		{
+			name: "NO_PROXY lists the proxy domain",
+			proxyStatus: configv1.ProxyStatus{
+				HTTPProxy: "http://proxy.example.com:3128",
+				NoProxy:   ".example.com",
+			},
+			expectedWarnings: `NO_PROXY entry ".example.com" matches the host of HTTP_PROXY "http://proxy.example.com:3128"`,
+		},
+		{
+			name: "invalid proxy URL",
+			proxyStatus: configv1.ProxyStatus{
+				HTTPProxy: "proxy.example.com:3128",
+				NoProxy:   ".svc",
+			},
+			expectedWarnings: `HTTP_PROXY "proxy.example.com:3128" is not a valid URL`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := &common.ConfigWarnings{}
+			validateProxyEnv(proxyConfigToEnvVars(&configv1.Proxy{Status: tt.proxyStatus}), warnings)
+
+			if got := warnings.ToCondition("ProxyWarning").Message; got != tt.expectedWarnings {
+				t.Errorf("expected warnings %q, got %q", tt.expectedWarnings, got)
+			}
+		})
+	}
+}
+
+func TestStartupStagger(t *testing.T) {
+	for _, tt := range []struct {
+		name                    string
+		unsupportedConfig       string
+		expectedMinReadySeconds int32
+		expectErr               bool
+	}{
+		{
+			name: "disabled by default",
+		},
+		{
+			name:                    "enabled",
+			unsupportedConfig:       `{"operator":{"deployment":{"startupStaggerSeconds":30}}}`,
+			expectedMinReadySeconds: 30,
+		},
+		{
+			name:              "negative",
+			unsupportedConfig: `{"operator":{"deployment":{"startupStaggerSeconds":-1}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "fraction",
+			unsupportedConfig: `{"operator":{"deployment":{"startupStaggerSeconds":1.5}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "too long",
+			unsupportedConfig: `{"operator":{"deployment":{"startupStaggerSeconds":3600}}}`,
+			expectErr:         true,
+		},
+		{
+			name:              "not a number",
+			unsupportedConfig: `{"operator":{"deployment":{"startupStaggerSeconds":"30s"}}}`,
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment, err := getOAuthServerDeployment(newTestOperatorConfig(tt.unsupportedConfig), &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if deployment.Spec.MinReadySeconds != tt.expectedMinReadySeconds {
+				t.Errorf("expected minReadySeconds %d, got %d", tt.expectedMinReadySeconds, deployment.Spec.MinReadySeconds)
+			}
+			if strategy := deployment.Spec.Strategy; strategy.RollingUpdate == nil || strategy.RollingUpdate.MaxSurge.IntValue() != 0 {
+				t.Errorf("expected the rolling update to replace one pod at a time, got %v", strategy)
+			}
+		})
+	}
+}
+
+func TestRenderFromCachedAsset(t *testing.T) {
+	first := renderFromCachedAsset()
+	first.Spec.Template.Spec.Containers[0].Args[0] = "modified"
+	first.Spec.Template.Annotations["modified"] = "true"
+
+	second := renderFromCachedAsset()
+	if second.Spec.Template.Spec.Containers[0].Args[0] == "modified" {
+		t.Error("expected a fresh copy of the asset, got the container args of a previous copy")
+	}
+	if _, ok := second.Spec.Template.Annotations["modified"]; ok {
+		t.Error("expected a fresh copy of the asset, got the annotations of a previous copy")
+	}
+}
+
+func TestGetOAuthServerDeploymentConcurrently(t *testing.T) {
+	const renders = 50
+
+	operatorConfig := newTestOperatorConfig(`{"operator":{"audit":{"logForwarding":{"enabled":true}},"deployment":{"sidecarInjectionAnnotations":{"example.com/inject":"false"}}}}`)
+
+	deployments := make([]*appsv1.Deployment, renders)
+	errs := make([]error, renders)
+	var wg sync.WaitGroup
+	for i := 0; i < renders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			deployments[i], errs[i] = getOAuthServerDeployment(operatorConfig, &configv1.Proxy{}, &configv1.Scheduler{}, &configv1.Infrastructure{}, false, "secrets:a:1")
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range deployments {
+		if errs[i] != nil {
+			t.Fatalf("render %d failed: %v", i, errs[i])
+		}
+		if !equality.Semantic.DeepEqual(deployments[0], deployments[i]) {
+			t.Fatalf("render %d differs from the first one: %s", i, cmp.Diff(deployments[0], deployments[i]))
+		}
+	}
+}
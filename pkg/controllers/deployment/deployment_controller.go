@@ -2,6 +2,8 @@ package deployment
 
 import (
 	"context"
+	"crypto/sha512"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strings"
@@ -11,14 +13,18 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 
 	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
 	configv1client "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
 	configinformer "github.com/openshift/client-go/config/informers/externalversions"
 	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
@@ -34,10 +40,17 @@ import (
 	"github.com/openshift/library-go/pkg/operator/status"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 	"github.com/openshift/library-go/pkg/route/routeapihelpers"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
+	observeoauth "github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/oauth"
 )
 
 var _ workload.Delegate = &oauthServerDeploymentSyncer{}
 
+// configWarningConditionType lists the non-fatal problems of the oauth-server
+// configuration in the operator status
+const configWarningConditionType = "OAuthServerConfigWarning"
+
 // nodeCountFunction a function to return count of nodes
 type nodeCountFunc func(nodeSelector map[string]string) (*int32, error)
 
@@ -54,17 +67,27 @@ type oauthServerDeploymentSyncer struct {
 	// one pod of a given replicaset from landing on a node.
 	ensureAtMostOnePodPerNode ensureAtMostOnePodPerNodeFunc
 
-	deployments appsv1client.DeploymentsGetter
-	auth        operatorv1client.AuthenticationsGetter
+	deployments      appsv1client.DeploymentsGetter
+	pods             corev1client.PodsGetter
+	deploymentLister appsv1listers.DeploymentLister
+	auth             operatorv1client.AuthenticationsGetter
 
 	configMapLister corev1listers.ConfigMapLister
 	secretLister    corev1listers.SecretLister
 	podsLister      corev1listers.PodLister
 	proxyLister     configv1listers.ProxyLister
+	schedulerLister configv1listers.SchedulerLister
+	infraLister     configv1listers.InfrastructureLister
 	routeLister     routev1listers.RouteLister
 
 	bootstrapUserDataGetter    bootstrap.BootstrapUserDataGetter
 	bootstrapUserChangeRollOut bool
+
+	// renderCache skips rendering the deployment when none of its inputs changed
+	renderCache renderCache
+	// appliedObservedConfig is the observed config of the last update of the
+	// deployment, used to attribute the next update to its changes
+	appliedObservedConfig []byte
 }
 
 func NewOAuthServerWorkloadController(
@@ -90,13 +113,17 @@ func NewOAuthServerWorkloadController(
 		countNodes:                countNodes,
 		ensureAtMostOnePodPerNode: ensureAtMostOnePodPerNode,
 
-		deployments: kubeClient.AppsV1(),
-		auth:        authOperatorGetter,
+		deployments:      kubeClient.AppsV1(),
+		pods:             kubeClient.CoreV1(),
+		deploymentLister: kubeInformersForTargetNamespace.Apps().V1().Deployments().Lister(),
+		auth:             authOperatorGetter,
 
 		configMapLister: kubeInformersForTargetNamespace.Core().V1().ConfigMaps().Lister(),
 		secretLister:    kubeInformersForTargetNamespace.Core().V1().Secrets().Lister(),
 		podsLister:      kubeInformersForTargetNamespace.Core().V1().Pods().Lister(),
 		proxyLister:     configInformers.Config().V1().Proxies().Lister(),
+		schedulerLister: configInformers.Config().V1().Schedulers().Lister(),
+		infraLister:     configInformers.Config().V1().Infrastructures().Lister(),
 		routeLister:     routeInformersForTargetNamespace.Route().V1().Routes().Lister(),
 
 		bootstrapUserDataGetter: bootstrapUserDataGetter,
@@ -109,7 +136,9 @@ func NewOAuthServerWorkloadController(
 		oauthDeploymentSyncer.bootstrapUserChangeRollOut = userExists
 	}
 
-	return workload.NewController(
+	resync := &resyncInformer{interval: resyncIntervalFromEnv()}
+
+	controller := workload.NewController(
 		"OAuthServer",
 		"cluster-authentication-operator",
 		targetNS,
@@ -122,7 +151,10 @@ func NewOAuthServerWorkloadController(
 		[]factory.Informer{
 			configInformers.Config().V1().Ingresses().Informer(),
 			configInformers.Config().V1().Proxies().Informer(),
+			configInformers.Config().V1().Schedulers().Informer(),
+			configInformers.Config().V1().Infrastructures().Informer(),
 			nodeInformer.Informer(),
+			resync,
 		},
 		[]factory.Informer{
 			kubeInformersForTargetNamespace.Apps().V1().Deployments().Informer(),
@@ -137,16 +169,18 @@ func NewOAuthServerWorkloadController(
 		eventsRecorder,
 		versionRecorder,
 	)
+
+	return &resyncController{Controller: controller, resync: resync}
 }
 
-func (c *oauthServerDeploymentSyncer) PreconditionFulfilled(_ context.Context) (bool, error) {
+func (c *oauthServerDeploymentSyncer) PreconditionFulfilled(ctx context.Context) (bool, error) {
 	route, err := c.routeLister.Routes("openshift-authentication").Get("oauth-openshift")
 	if err != nil {
-		return false, fmt.Errorf("waiting for the oauth-openshift route to appear: %w", err)
+		return false, c.routeNotAdmitted(ctx, "RouteNotFound", fmt.Errorf("waiting for the oauth-openshift route to appear: %w", err))
 	}
 
 	if _, _, err := routeapihelpers.IngressURI(route, ""); err != nil {
-		return false, fmt.Errorf("waiting for the oauth-openshift route to contain an admitted ingress: %w", err)
+		return false, c.routeNotAdmitted(ctx, "RouteNotAdmitted", fmt.Errorf("waiting for the oauth-openshift route to contain an admitted ingress: %w", err))
 	}
 
 	return true, nil
@@ -165,6 +199,16 @@ func (c *oauthServerDeploymentSyncer) Sync(ctx context.Context, syncContext fact
 		return nil, false, append(errs, err)
 	}
 
+	schedulerConfig, err := c.getSchedulerConfig()
+	if err != nil {
+		return nil, false, append(errs, err)
+	}
+
+	infrastructureConfig, err := c.getInfrastructureConfig()
+	if err != nil {
+		return nil, false, append(errs, err)
+	}
+
 	// resourceVersions serves to store versions of config resources so that we
 	// can redeploy our payload should either change. We only omit the operator
 	// config version, it would both cause redeploy loops (status updates cause
@@ -177,7 +221,28 @@ func (c *oauthServerDeploymentSyncer) Sync(ctx context.Context, syncContext fact
 		resourceVersions = append(resourceVersions, "proxy:"+proxyConfig.Name+":"+proxyConfig.ResourceVersion)
 	}
 
-	configResourceVersions, err := c.getConfigResourceVersions()
+	unsupportedConfig, err := common.UnsupportedConfigOverrides(&operatorConfig.Spec.OperatorSpec)
+	if err != nil {
+		return nil, false, append(errs, fmt.Errorf("failed to decode unsupportedConfigOverrides: %w", err))
+	}
+
+	if err := c.updateConfigWarnings(ctx, operatorConfig, proxyConfig, unsupportedConfig); err != nil {
+		errs = append(errs, err)
+	}
+
+	// resources whose content changes trigger a targeted restart are not
+	// tracked by their resource versions
+	restartResources, err := restartOnContentChangeResources(unsupportedConfig)
+	if err != nil {
+		return nil, false, append(errs, err)
+	}
+
+	pullFailureGracePeriod, err := imagePullFailureGracePeriod(unsupportedConfig)
+	if err != nil {
+		return nil, false, append(errs, err)
+	}
+
+	configResourceVersions, err := c.getConfigResourceVersions(restartResources)
 	if err != nil {
 		return nil, false, append(errs, err)
 	}
@@ -195,12 +260,17 @@ func (c *oauthServerDeploymentSyncer) Sync(ctx context.Context, syncContext fact
 	}
 
 	// deployment, have RV of all resources
-	expectedDeployment, err := getOAuthServerDeployment(operatorConfig, proxyConfig, c.bootstrapUserChangeRollOut, resourceVersions...)
+	expectedDeployment, err := c.renderCache.render(operatorConfig, proxyConfig, schedulerConfig, infrastructureConfig, c.bootstrapUserChangeRollOut, resourceVersions...)
 	if err != nil {
-		return nil, false, append(errs, err)
+		return c.holdLastKnownGoodDeployment(err)
 	}
 
 	if _, err := c.secretLister.Secrets("openshift-authentication").Get("v4-0-config-system-custom-router-certs"); err == nil {
+		container, err := oauthServerContainer(&expectedDeployment.Spec.Template.Spec)
+		if err != nil {
+			return nil, false, append(errs, err)
+		}
+
 		expectedDeployment.Spec.Template.Spec.Volumes = append(expectedDeployment.Spec.Template.Spec.Volumes, corev1.Volume{
 			Name: "v4-0-config-system-custom-router-certs",
 			VolumeSource: corev1.VolumeSource{
@@ -209,11 +279,23 @@ func (c *oauthServerDeploymentSyncer) Sync(ctx context.Context, syncContext fact
 				},
 			},
 		})
-		expectedDeployment.Spec.Template.Spec.Containers[0].VolumeMounts = append(expectedDeployment.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
 			Name:      "v4-0-config-system-custom-router-certs",
 			ReadOnly:  true,
 			MountPath: "/var/config/system/secrets/v4-0-config-system-custom-router-certs",
 		})
+
+		if err := validatePodSpec(&expectedDeployment.Spec.Template.Spec); err != nil {
+			return c.holdLastKnownGoodDeployment(err)
+		}
+	}
+
+	if restartResources.Len() > 0 {
+		contentHash, err := c.restartContentHash(restartResources)
+		if err != nil {
+			return nil, false, append(errs, err)
+		}
+		expectedDeployment.Spec.Template.Annotations[restartContentHashAnnotation] = contentHash
 	}
 
 	err = c.ensureAtMostOnePodPerNode(&expectedDeployment.Spec, "oauth-openshift")
@@ -228,7 +310,7 @@ func (c *oauthServerDeploymentSyncer) Sync(ctx context.Context, syncContext fact
 	}
 	expectedDeployment.Spec.Replicas = masterNodeCount
 
-	deployment, _, err := resourceapply.ApplyDeployment(ctx, c.deployments,
+	deployment, updated, err := resourceapply.ApplyDeployment(ctx, c.deployments,
 		syncContext.Recorder(),
 		expectedDeployment,
 		resourcemerge.ExpectedDeploymentGeneration(expectedDeployment, operatorConfig.Status.Generations),
@@ -236,10 +318,63 @@ func (c *oauthServerDeploymentSyncer) Sync(ctx context.Context, syncContext fact
 	if err != nil {
 		return nil, false, append(errs, fmt.Errorf("applying deployment of the integrated OAuth server failed: %w", err))
 	}
+	if updated {
+		if err := recordRolloutReason(syncContext.Recorder(), c.appliedObservedConfig, operatorConfig.Spec.ObservedConfig.Raw); err != nil {
+			klog.Warningf("unable to attribute the update of the oauth-server deployment: %v", err)
+		}
+		c.appliedObservedConfig = operatorConfig.Spec.ObservedConfig.Raw
+	} else if c.appliedObservedConfig == nil {
+		c.appliedObservedConfig = operatorConfig.Spec.ObservedConfig.Raw
+	}
+
+	// pods that cannot pull their image would only be reported as unavailable
+	pullFailures, err := c.imagePullFailures(deployment, pullFailureGracePeriod)
+	if err != nil {
+		return deployment, true, append(errs, fmt.Errorf("unable to check the oauth-server pods for image pull failures: %w", err))
+	}
+	errs = append(errs, pullFailures...)
+
+	// Sync only runs once the route is admitted, see PreconditionFulfilled
+	if err := c.syncRouteAdmittedCondition(ctx, deployment, corev1.ConditionTrue, "RouteAdmitted", ""); err != nil {
+		errs = append(errs, err)
+	}
 
 	return deployment, true, errs
 }
 
+// updateConfigWarnings reports the current warnings about the oauth-server
+// configuration in the configWarningConditionType condition, which goes back
+// to False once the warnings are resolved.
+func (c *oauthServerDeploymentSyncer) updateConfigWarnings(ctx context.Context, operatorConfig *operatorv1.Authentication, proxyConfig *configv1.Proxy, unsupportedConfig map[string]interface{}) error {
+	warnings, err := serverArgumentWarnings(operatorConfig, unsupportedConfig)
+	if err != nil {
+		return err
+	}
+	validateProxyEnv(proxyConfigToEnvVars(proxyConfig), warnings)
+
+	_, _, err = v1helpers.UpdateStatus(ctx, c.operatorClient, v1helpers.UpdateConditionFn(warnings.ToCondition(configWarningConditionType)))
+	return err
+}
+
+// holdLastKnownGoodDeployment is used when the deployment cannot be rendered,
+// e.g. because the observed config does not pass validation. Rather than
+// applying a broken deployment that would only crashloop, the currently running
+// deployment is kept and reported on, while the render error degrades the
+// workload. The operator config is not reported to be at the highest
+// generation so that no operand version is set for the held deployment.
+func (c *oauthServerDeploymentSyncer) holdLastKnownGoodDeployment(renderErr error) (*appsv1.Deployment, bool, []error) {
+	err := fmt.Errorf("unable to render the deployment of the integrated OAuth server, keeping the current deployment: %w", renderErr)
+
+	deployment, getErr := c.deploymentLister.Deployments("openshift-authentication").Get("oauth-openshift")
+	if errors.IsNotFound(getErr) {
+		return nil, false, []error{err}
+	} else if getErr != nil {
+		return nil, false, []error{err, getErr}
+	}
+
+	return deployment.DeepCopy(), false, []error{err}
+}
+
 func (c *oauthServerDeploymentSyncer) getProxyConfig() (*configv1.Proxy, error) {
 	proxyConfig, err := c.proxyLister.Get("cluster")
 	if err != nil {
@@ -252,7 +387,34 @@ func (c *oauthServerDeploymentSyncer) getProxyConfig() (*configv1.Proxy, error)
 	return proxyConfig, nil
 }
 
-func (c *oauthServerDeploymentSyncer) getConfigResourceVersions() ([]string, error) {
+func (c *oauthServerDeploymentSyncer) getSchedulerConfig() (*configv1.Scheduler, error) {
+	schedulerConfig, err := c.schedulerLister.Get("cluster")
+	if err != nil {
+		if errors.IsNotFound(err) {
+			klog.V(4).Infof("No scheduler configuration found, defaulting to empty")
+			return &configv1.Scheduler{}, nil
+		}
+		return nil, fmt.Errorf("unable to get cluster scheduler configuration: %v", err)
+	}
+	return schedulerConfig, nil
+}
+
+func (c *oauthServerDeploymentSyncer) getInfrastructureConfig() (*configv1.Infrastructure, error) {
+	infrastructureConfig, err := c.infraLister.Get("cluster")
+	if err != nil {
+		if errors.IsNotFound(err) {
+			klog.V(4).Infof("No infrastructure configuration found, defaulting to empty")
+			return &configv1.Infrastructure{}, nil
+		}
+		return nil, fmt.Errorf("unable to get cluster infrastructure configuration: %v", err)
+	}
+	return infrastructureConfig, nil
+}
+
+// getConfigResourceVersions returns the resource versions of the configmaps and
+// secrets the oauth-server consumes, except for the excluded ones given as
+// "configmaps/<name>" or "secrets/<name>".
+func (c *oauthServerDeploymentSyncer) getConfigResourceVersions(excluded sets.String) ([]string, error) {
 	var configRVs []string
 
 	configMaps, err := c.configMapLister.ConfigMaps("openshift-authentication").List(labels.Everything())
@@ -260,7 +422,11 @@ func (c *oauthServerDeploymentSyncer) getConfigResourceVersions() ([]string, err
 		return nil, fmt.Errorf("unable to list configmaps in %q namespace: %v", "openshift-authentication", err)
 	}
 	for _, cm := range configMaps {
-		if strings.HasPrefix(cm.Name, "v4-0-config-") {
+		if excluded.Has("configmaps/" + cm.Name) {
+			continue
+		}
+		// the audit policy is only read on startup of the oauth-server
+		if strings.HasPrefix(cm.Name, "v4-0-config-") || cm.Name == observeoauth.AuditPolicyConfigMapName {
 			// prefix the RV to make it clear where it came from since each resource can be from different etcd
 			configRVs = append(configRVs, "configmaps:"+cm.Name+":"+cm.ResourceVersion)
 		}
@@ -271,6 +437,9 @@ func (c *oauthServerDeploymentSyncer) getConfigResourceVersions() ([]string, err
 		return nil, fmt.Errorf("unable to list secrets in %q namespace: %v", "openshift-authentication", err)
 	}
 	for _, secret := range secrets {
+		if excluded.Has("secrets/" + secret.Name) {
+			continue
+		}
 		if strings.HasPrefix(secret.Name, "v4-0-config-") {
 			// prefix the RV to make it clear where it came from since each resource can be from different etcd
 			configRVs = append(configRVs, "secrets:"+secret.Name+":"+secret.ResourceVersion)
@@ -279,3 +448,51 @@ func (c *oauthServerDeploymentSyncer) getConfigResourceVersions() ([]string, err
 
 	return configRVs, nil
 }
+
+// restartContentHash returns a hash of the content of the given resources in
+// the openshift-authentication namespace, given as "configmaps/<name>" or
+// "secrets/<name>". Unlike resource versions, the hash only changes when the
+// data of the resources does.
+func (c *oauthServerDeploymentSyncer) restartContentHash(resources sets.String) (string, error) {
+	hash := sha512.New()
+	for _, resource := range resources.List() {
+		kind, name, _ := strings.Cut(resource, "/")
+
+		var data map[string][]byte
+		switch kind {
+		case "configmaps":
+			cm, err := c.configMapLister.ConfigMaps("openshift-authentication").Get(name)
+			if errors.IsNotFound(err) {
+				fmt.Fprintf(hash, "%s:missing\n", resource)
+				continue
+			} else if err != nil {
+				return "", fmt.Errorf("unable to get configmap %q: %w", name, err)
+			}
+			data = map[string][]byte{}
+			for k, v := range cm.Data {
+				data[k] = []byte(v)
+			}
+			for k, v := range cm.BinaryData {
+				data[k] = v
+			}
+		case "secrets":
+			secret, err := c.secretLister.Secrets("openshift-authentication").Get(name)
+			if errors.IsNotFound(err) {
+				fmt.Fprintf(hash, "%s:missing\n", resource)
+				continue
+			} else if err != nil {
+				return "", fmt.Errorf("unable to get secret %q: %w", name, err)
+			}
+			data = secret.Data
+		}
+
+		// keys are sorted to get a stable hash
+		for _, k := range sets.StringKeySet(data).List() {
+			fmt.Fprintf(hash, "%s:%s:%d:", resource, k, len(data[k]))
+			hash.Write(data[k])
+			hash.Write([]byte("\n"))
+		}
+	}
+
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)), nil
+}
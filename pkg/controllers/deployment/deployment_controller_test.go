@@ -0,0 +1,353 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	utilpointer "k8s.io/utils/pointer"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	operatorv1client "github.com/openshift/client-go/operator/clientset/versioned/typed/operator/v1"
+	routev1listers "github.com/openshift/client-go/route/listers/route/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+func TestHoldLastKnownGoodDeployment(t *testing.T) {
+	renderErr := fmt.Errorf("unknown server arguments: [foo]")
+
+	for _, tt := range []struct {
+		name               string
+		existing           *appsv1.Deployment
+		expectedDeployment bool
+	}{
+		{
+			name: "current deployment is held",
+			existing: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "oauth-openshift",
+					Namespace:   "openshift-authentication",
+					Annotations: map[string]string{"operator.openshift.io/rvs-hash": "last-known-good"},
+				},
+			},
+			expectedDeployment: true,
+		},
+		{
+			name: "no deployment to hold",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if tt.existing != nil {
+				if err := indexer.Add(tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := &oauthServerDeploymentSyncer{deploymentLister: appsv1listers.NewDeploymentLister(indexer)}
+
+			deployment, atHighestGeneration, errs := c.holdLastKnownGoodDeployment(renderErr)
+			if atHighestGeneration {
+				t.Errorf("expected the operator config to not be reported at the highest generation")
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), renderErr.Error()) {
+				t.Errorf("expected the render error to be reported, got %v", errs)
+			}
+
+			if !tt.expectedDeployment {
+				if deployment != nil {
+					t.Errorf("expected no deployment, got %v", deployment)
+				}
+				return
+			}
+
+			if deployment == nil {
+				t.Fatal("expected the current deployment to be held")
+			}
+			if got := deployment.Annotations["operator.openshift.io/rvs-hash"]; got != "last-known-good" {
+				t.Errorf("expected the last known good deployment, got rvs-hash %q", got)
+			}
+		})
+	}
+}
+
+func TestRestartOnContentChange(t *testing.T) {
+	htpasswd := func(content, resourceVersion string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "v4-0-config-user-idp-0-file-data", Namespace: "openshift-authentication", ResourceVersion: resourceVersion},
+			Data:       map[string][]byte{"htpasswd": []byte(content)},
+		}
+	}
+	session := func(content, resourceVersion string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "v4-0-config-system-session", Namespace: "openshift-authentication", ResourceVersion: resourceVersion},
+			Data:       map[string][]byte{"v4-0-config-system-session": []byte(content)},
+		}
+	}
+
+	restartResources, err := restartOnContentChangeResources(map[string]interface{}{
+		"deployment": map[string]interface{}{
+			"restartOnContentChange": []interface{}{"secrets/v4-0-config-user-idp-0-file-data"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := func(secrets ...*corev1.Secret) (string, string) {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		for _, secret := range secrets {
+			if err := indexer.Add(secret); err != nil {
+				t.Fatal(err)
+			}
+		}
+		c := &oauthServerDeploymentSyncer{
+			configMapLister: corev1listers.NewConfigMapLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+			secretLister:    corev1listers.NewSecretLister(indexer),
+		}
+
+		rvs, err := c.getConfigResourceVersions(restartResources)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentHash, err := c.restartContentHash(restartResources)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resourceVersionsHash(rvs...), contentHash
+	}
+
+	initialRVsHash, initialContentHash := state(htpasswd("user:a", "1"), session("s", "2"))
+
+	t.Run("subset content change", func(t *testing.T) {
+		rvsHash, contentHash := state(htpasswd("user:b", "3"), session("s", "2"))
+		if contentHash == initialContentHash {
+			t.Errorf("expected the content hash to change")
+		}
+		if rvsHash != initialRVsHash {
+			t.Errorf("expected the rvs-hash to not change")
+		}
+	})
+
+	t.Run("subset resource version change only", func(t *testing.T) {
+		rvsHash, contentHash := state(htpasswd("user:a", "3"), session("s", "2"))
+		if contentHash != initialContentHash {
+			t.Errorf("expected the content hash to not change")
+		}
+		if rvsHash != initialRVsHash {
+			t.Errorf("expected the rvs-hash to not change")
+		}
+	})
+
+	t.Run("other resource change", func(t *testing.T) {
+		rvsHash, contentHash := state(htpasswd("user:a", "1"), session("t", "4"))
+		if contentHash != initialContentHash {
+			t.Errorf("expected the content hash to not change")
+		}
+		if rvsHash == initialRVsHash {
+			t.Errorf("expected the rvs-hash to change")
+		}
+	})
+}
+
+func TestRestartOnContentChangeResources(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		resources []interface{}
+		expectErr bool
+	}{
+		{name: "secret", resources: []interface{}{"secrets/htpasswd"}},
+		{name: "configmap", resources: []interface{}{"configmaps/ca"}},
+		{name: "unsupported kind", resources: []interface{}{"routes/oauth-openshift"}, expectErr: true},
+		{name: "missing name", resources: []interface{}{"secrets/"}, expectErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := restartOnContentChangeResources(map[string]interface{}{
+				"deployment": map[string]interface{}{"restartOnContentChange": tt.resources},
+			})
+			if tt.expectErr != (err != nil) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestUpdateConfigWarnings(t *testing.T) {
+	operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{}, &operatorv1.OperatorStatus{}, nil)
+	c := &oauthServerDeploymentSyncer{operatorClient: operatorClient}
+
+	// the warnings of every step replace the ones of the previous step
+	for _, step := range []struct {
+		name              string
+		observedConfig    string
+		unsupportedConfig map[string]interface{}
+		expectedStatus    operatorv1.ConditionStatus
+		expectedMessage   string
+	}{
+		{
+			name:           "no warnings",
+			observedConfig: testObservedConfig,
+			expectedStatus: operatorv1.ConditionFalse,
+		},
+		{
+			name:            "unknown server arguments",
+			observedConfig:  `{"oauthServer":{"serverArguments":{"audit-log-pathh":["/var/log/oauth-server/audit.log"],"audit-log-format":["json"],"foo":["bar"]}}}`,
+			expectedStatus:  operatorv1.ConditionTrue,
+			expectedMessage: "server argument \"audit-log-pathh\" is not known to the oauth-server\nserver argument \"foo\" is not known to the oauth-server",
+		},
+		{
+			name:              "validation skipped",
+			observedConfig:    `{"oauthServer":{"serverArguments":{"foo":["bar"]}}}`,
+			unsupportedConfig: map[string]interface{}{"deployment": map[string]interface{}{"skipServerArgumentsValidation": true}},
+			expectedStatus:    operatorv1.ConditionTrue,
+			expectedMessage:   "server arguments are not validated as deployment.skipServerArgumentsValidation is set",
+		},
+		{
+			name:           "warnings cleared",
+			observedConfig: testObservedConfig,
+			expectedStatus: operatorv1.ConditionFalse,
+		},
+	} {
+		operatorConfig := &operatorv1.Authentication{}
+		operatorConfig.Spec.ObservedConfig = runtime.RawExtension{Raw: []byte(step.observedConfig)}
+
+		if err := c.updateConfigWarnings(context.Background(), operatorConfig, &configv1.Proxy{}, step.unsupportedConfig); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		_, status, _, err := operatorClient.GetOperatorState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		condition := v1helpers.FindOperatorCondition(status.Conditions, "OAuthServerConfigWarning")
+		if condition == nil {
+			t.Fatalf("%s: expected the OAuthServerConfigWarning condition", step.name)
+		}
+		if condition.Status != step.expectedStatus || condition.Message != step.expectedMessage {
+			t.Errorf("%s: expected condition status %s with message %q, got %s with %q", step.name, step.expectedStatus, step.expectedMessage, condition.Status, condition.Message)
+		}
+	}
+}
+
+func TestSyncHoldsDeploymentOnInvalidPodSpec(t *testing.T) {
+	const routerCertsPath = "/var/config/system/secrets/v4-0-config-system-custom-router-certs"
+
+	// the audit configmap is moved to where the custom router certs are mounted
+	// after the deployment is rendered
+	operatorConfig := newTestOperatorConfig(`{"operator":{"audit":{"policyMountPath":"` + routerCertsPath + `"}}}`)
+	operatorConfig.Name = "cluster"
+	operatorConfig.Spec.ObservedConfig = runtime.RawExtension{Raw: []byte(strings.Replace(testObservedConfig, "/var/run/configmaps/audit/", routerCertsPath+"/", 1))}
+
+	existing := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "oauth-openshift",
+			Namespace:   "openshift-authentication",
+			Annotations: map[string]string{"operator.openshift.io/rvs-hash": "last-known-good"},
+		},
+	}
+	routerCerts := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "v4-0-config-system-custom-router-certs", Namespace: "openshift-authentication"}}
+
+	c, kubeClient := newTestSyncer(t, operatorConfig, existing, routerCerts)
+
+	deployment, atHighestGeneration, errs := c.Sync(context.TODO(), factory.NewSyncContext(t.Name(), events.NewInMemoryRecorder(t.Name())))
+	if atHighestGeneration {
+		t.Errorf("expected the operator config to not be reported at the highest generation")
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "keeping the current deployment") || !strings.Contains(errs[0].Error(), "is already mounted at this path") {
+		t.Errorf("expected the invalid pod spec to be reported, got %v", errs)
+	}
+	if deployment == nil || deployment.Annotations["operator.openshift.io/rvs-hash"] != "last-known-good" {
+		t.Errorf("expected the last known good deployment to be held, got %v", deployment)
+	}
+	for _, action := range kubeClient.Actions() {
+		if action.GetResource().Resource == "deployments" && action.GetVerb() != "get" {
+			t.Errorf("expected the deployment to not be applied, got %s", action.GetVerb())
+		}
+	}
+}
+
+// newTestSyncer returns a syncer that renders the deployment from the given
+// operator config. The objects are added to the listers of the syncer, the
+// kube objects to its client, too.
+func newTestSyncer(t *testing.T, operatorConfig *operatorv1.Authentication, objects ...runtime.Object) (*oauthServerDeploymentSyncer, *fake.Clientset) {
+	var deployments, pods, secrets, routes, kubeObjects []runtime.Object
+	for _, obj := range objects {
+		switch obj.(type) {
+		case *appsv1.Deployment:
+			deployments = append(deployments, obj)
+		case *corev1.Pod:
+			pods = append(pods, obj)
+		case *corev1.Secret:
+			secrets = append(secrets, obj)
+		case *routev1.Route:
+			routes = append(routes, obj)
+			continue
+		default:
+			t.Fatalf("unexpected object %T", obj)
+		}
+		kubeObjects = append(kubeObjects, obj)
+	}
+	kubeClient := fake.NewSimpleClientset(kubeObjects...)
+
+	return &oauthServerDeploymentSyncer{
+		operatorClient:            v1helpers.NewFakeOperatorClient(&operatorConfig.Spec.OperatorSpec, &operatorv1.OperatorStatus{}, nil),
+		countNodes:                func(map[string]string) (*int32, error) { return utilpointer.Int32(3), nil },
+		ensureAtMostOnePodPerNode: func(*appsv1.DeploymentSpec, string) error { return nil },
+
+		deployments:      kubeClient.AppsV1(),
+		pods:             kubeClient.CoreV1(),
+		deploymentLister: appsv1listers.NewDeploymentLister(newTestIndexer(t, deployments...)),
+		auth:             &fakeAuthentications{authentication: operatorConfig},
+
+		configMapLister: corev1listers.NewConfigMapLister(newTestIndexer(t)),
+		secretLister:    corev1listers.NewSecretLister(newTestIndexer(t, secrets...)),
+		podsLister:      corev1listers.NewPodLister(newTestIndexer(t, pods...)),
+		proxyLister:     configv1listers.NewProxyLister(newTestIndexer(t)),
+		schedulerLister: configv1listers.NewSchedulerLister(newTestIndexer(t)),
+		infraLister:     configv1listers.NewInfrastructureLister(newTestIndexer(t)),
+		routeLister:     routev1listers.NewRouteLister(newTestIndexer(t, routes...)),
+	}, kubeClient
+}
+
+// fakeAuthentications returns the authentication operator config and
+// implements nothing else
+type fakeAuthentications struct {
+	operatorv1client.AuthenticationInterface
+	authentication *operatorv1.Authentication
+}
+
+func (f *fakeAuthentications) Authentications() operatorv1client.AuthenticationInterface {
+	return f
+}
+
+func (f *fakeAuthentications) Get(_ context.Context, name string, _ metav1.GetOptions) (*operatorv1.Authentication, error) {
+	if name != f.authentication.Name {
+		return nil, errors.NewNotFound(operatorv1.Resource("authentications"), name)
+	}
+	return f.authentication.DeepCopy(), nil
+}
+
+func newTestIndexer(t *testing.T, objects ...runtime.Object) cache.Indexer {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objects {
+		if err := indexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return indexer
+}
@@ -0,0 +1,91 @@
+package deployment
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+)
+
+// renderInputs are all inputs to rendering the oauth-server deployment
+type renderInputs struct {
+	OperatorSpec         operatorv1.AuthenticationSpec `json:"operatorSpec"`
+	ProxyStatus          configv1.ProxyStatus          `json:"proxyStatus"`
+	SchedulerSpec        configv1.SchedulerSpec        `json:"schedulerSpec"`
+	InfrastructureStatus configv1.InfrastructureStatus `json:"infrastructureStatus"`
+	BootstrapUserExists  bool                          `json:"bootstrapUserExists"`
+	ResourceVersionsHash string                        `json:"resourceVersionsHash"`
+}
+
+// renderFingerprint returns a stable fingerprint of the inputs to rendering the
+// oauth-server deployment. The resource versions of the tracked resources stand
+// in for their content. The order of the resource versions does not matter.
+func renderFingerprint(
+	operatorConfig *operatorv1.Authentication,
+	proxyConfig *configv1.Proxy,
+	schedulerConfig *configv1.Scheduler,
+	infrastructureConfig *configv1.Infrastructure,
+	bootstrapUserExists bool,
+	resourceVersions ...string,
+) (string, error) {
+	inputs := renderInputs{
+		OperatorSpec:         operatorConfig.Spec,
+		ProxyStatus:          proxyConfig.Status,
+		SchedulerSpec:        schedulerConfig.Spec,
+		InfrastructureStatus: infrastructureConfig.Status,
+		BootstrapUserExists:  bootstrapUserExists,
+		// the hash of the resource versions is independent of their order
+		ResourceVersionsHash: resourceVersionsHash(resourceVersions...),
+	}
+
+	// encoding/json sorts map keys, which keeps the encoding stable
+	encoded, err := json.Marshal(inputs)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode the deployment render inputs: %w", err)
+	}
+
+	fingerprint := sha512.Sum512(encoded)
+	return base64.RawURLEncoding.EncodeToString(fingerprint[:]), nil
+}
+
+// renderCache keeps the last rendered oauth-server deployment along with the
+// fingerprint of its inputs so that it is only rendered again when any of the
+// inputs changed
+type renderCache struct {
+	fingerprint string
+	deployment  *appsv1.Deployment
+}
+
+// render returns a copy of the cached deployment if the inputs did not change
+// since it was rendered, and renders the deployment otherwise. Render errors
+// are not cached.
+func (c *renderCache) render(
+	operatorConfig *operatorv1.Authentication,
+	proxyConfig *configv1.Proxy,
+	schedulerConfig *configv1.Scheduler,
+	infrastructureConfig *configv1.Infrastructure,
+	bootstrapUserExists bool,
+	resourceVersions ...string,
+) (*appsv1.Deployment, error) {
+	fingerprint, err := renderFingerprint(operatorConfig, proxyConfig, schedulerConfig, infrastructureConfig, bootstrapUserExists, resourceVersions...)
+	if err != nil {
+		return nil, err
+	}
+	if c.deployment != nil && c.fingerprint == fingerprint {
+		return c.deployment.DeepCopy(), nil
+	}
+
+	deployment, err := getOAuthServerDeployment(operatorConfig, proxyConfig, schedulerConfig, infrastructureConfig, bootstrapUserExists, resourceVersions...)
+	if err != nil {
+		c.fingerprint, c.deployment = "", nil
+		return nil, err
+	}
+
+	c.fingerprint, c.deployment = fingerprint, deployment.DeepCopy()
+	return deployment, nil
+}
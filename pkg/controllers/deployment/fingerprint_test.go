@@ -0,0 +1,181 @@
+package deployment
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+)
+
+type fingerprintInputs struct {
+	operatorConfig       *operatorv1.Authentication
+	proxyConfig          *configv1.Proxy
+	schedulerConfig      *configv1.Scheduler
+	infrastructureConfig *configv1.Infrastructure
+	bootstrapUserExists  bool
+	resourceVersions     []string
+}
+
+func newFingerprintInputs() *fingerprintInputs {
+	return &fingerprintInputs{
+		operatorConfig:       newTestOperatorConfig(`{"operator":{"deployment":{"guaranteedQoS":true}}}`),
+		proxyConfig:          &configv1.Proxy{Status: configv1.ProxyStatus{HTTPSProxy: "https://proxy.example.com"}},
+		schedulerConfig:      &configv1.Scheduler{},
+		infrastructureConfig: &configv1.Infrastructure{},
+		resourceVersions:     []string{"configmaps:audit:1", "secrets:v4-0-config-system-session:2"},
+	}
+}
+
+func (i *fingerprintInputs) fingerprint(t *testing.T) string {
+	t.Helper()
+	fingerprint, err := renderFingerprint(i.operatorConfig, i.proxyConfig, i.schedulerConfig, i.infrastructureConfig, i.bootstrapUserExists, i.resourceVersions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fingerprint
+}
+
+func TestRenderFingerprint(t *testing.T) {
+	expected := newFingerprintInputs().fingerprint(t)
+
+	for _, tt := range []struct {
+		name          string
+		modify        func(*fingerprintInputs)
+		expectChanged bool
+	}{
+		{
+			name:   "no-op",
+			modify: func(*fingerprintInputs) {},
+		},
+		{
+			name: "reordered resource versions",
+			modify: func(i *fingerprintInputs) {
+				i.resourceVersions = []string{i.resourceVersions[1], i.resourceVersions[0]}
+			},
+		},
+		{
+			name: "operator config metadata",
+			modify: func(i *fingerprintInputs) {
+				i.operatorConfig.ResourceVersion = "42"
+				i.operatorConfig.Status.ObservedGeneration = 3
+			},
+		},
+		{
+			name: "observed config",
+			modify: func(i *fingerprintInputs) {
+				i.operatorConfig.Spec.ObservedConfig = runtime.RawExtension{Raw: []byte(`{"oauthServer":{}}`)}
+			},
+			expectChanged: true,
+		},
+		{
+			name: "unsupported config overrides",
+			modify: func(i *fingerprintInputs) {
+				i.operatorConfig.Spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(`{}`)}
+			},
+			expectChanged: true,
+		},
+		{
+			name: "log level",
+			modify: func(i *fingerprintInputs) {
+				i.operatorConfig.Spec.LogLevel = operatorv1.Debug
+			},
+			expectChanged: true,
+		},
+		{
+			name: "proxy",
+			modify: func(i *fingerprintInputs) {
+				i.proxyConfig.Status.NoProxy = ".cluster.local"
+			},
+			expectChanged: true,
+		},
+		{
+			name: "scheduler",
+			modify: func(i *fingerprintInputs) {
+				i.schedulerConfig.Spec.DefaultNodeSelector = "type=infra"
+			},
+			expectChanged: true,
+		},
+		{
+			name: "infrastructure",
+			modify: func(i *fingerprintInputs) {
+				i.infrastructureConfig.Status.ControlPlaneTopology = configv1.SingleReplicaTopologyMode
+			},
+			expectChanged: true,
+		},
+		{
+			name: "bootstrap user",
+			modify: func(i *fingerprintInputs) {
+				i.bootstrapUserExists = true
+			},
+			expectChanged: true,
+		},
+		{
+			name: "tracked resource changed",
+			modify: func(i *fingerprintInputs) {
+				i.resourceVersions[0] = "configmaps:audit:5"
+			},
+			expectChanged: true,
+		},
+		{
+			name: "tracked resource added",
+			modify: func(i *fingerprintInputs) {
+				i.resourceVersions = append(i.resourceVersions, "secrets:v4-0-config-user-idp-0-file-data:1")
+			},
+			expectChanged: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs := newFingerprintInputs()
+			tt.modify(inputs)
+
+			if changed := inputs.fingerprint(t) != expected; changed != tt.expectChanged {
+				t.Errorf("expected the fingerprint to change: %t, got changed: %t", tt.expectChanged, changed)
+			}
+		})
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	cache := &renderCache{}
+	inputs := newFingerprintInputs()
+	render := func() string {
+		t.Helper()
+		deployment, err := cache.render(inputs.operatorConfig, inputs.proxyConfig, inputs.schedulerConfig, inputs.infrastructureConfig, inputs.bootstrapUserExists, inputs.resourceVersions...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := getOAuthServerDeployment(inputs.operatorConfig, inputs.proxyConfig, inputs.schedulerConfig, inputs.infrastructureConfig, inputs.bootstrapUserExists, inputs.resourceVersions...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, deployment) {
+			t.Errorf("expected the rendered deployment to match a fresh render")
+		}
+
+		// callers may modify the returned deployment
+		deployment.Spec.Template.Annotations["modified"] = "true"
+		return cache.fingerprint
+	}
+
+	first := render()
+	if render() != first {
+		t.Errorf("expected the cached render to be reused")
+	}
+
+	inputs.operatorConfig.Spec.LogLevel = operatorv1.Trace
+	if render() == first {
+		t.Errorf("expected a new render on changed inputs")
+	}
+
+	inputs.operatorConfig = newTestOperatorConfig(`{"operator":{"deployment":{"strategy":"BlueGreen"}}}`)
+	if _, err := cache.render(inputs.operatorConfig, inputs.proxyConfig, inputs.schedulerConfig, inputs.infrastructureConfig, inputs.bootstrapUserExists, inputs.resourceVersions...); err == nil {
+		t.Fatal("expected a render error")
+	}
+	if cache.deployment != nil {
+		t.Errorf("expected render errors to invalidate the cache")
+	}
+}
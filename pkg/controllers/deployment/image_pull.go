@@ -0,0 +1,88 @@
+package deployment
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// defaultImagePullFailureGracePeriod is how long the pulls of a pod may keep
+// failing before the failure is reported. Pulls are retried with a backoff and
+// a registry hiccup should not degrade the operator.
+const defaultImagePullFailureGracePeriod = 5 * time.Minute
+
+var (
+	imagePullFailureGracePeriodPath = []string{"deployment", "imagePullFailureGracePeriod"}
+
+	// imagePullFailureReasons are the reasons of a waiting container whose
+	// image cannot be pulled
+	imagePullFailureReasons = sets.NewString(
+		"ErrImagePull",
+		"ImagePullBackOff",
+		"ErrImageNeverPull",
+		"InvalidImageName",
+	)
+)
+
+// imagePullFailureGracePeriod returns the grace period before image pull
+// failures are reported. It can be set via deployment.imagePullFailureGracePeriod
+// in unsupportedConfigOverrides.operator, "0s" reports the failures right away.
+func imagePullFailureGracePeriod(unsupportedConfig map[string]interface{}) (time.Duration, error) {
+	fieldName := strings.Join(imagePullFailureGracePeriodPath, ".")
+
+	value, found, err := unstructured.NestedString(unsupportedConfig, imagePullFailureGracePeriodPath...)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read %s: %w", fieldName, err)
+	}
+	if !found || len(value) == 0 {
+		return defaultImagePullFailureGracePeriod, nil
+	}
+
+	gracePeriod, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fieldName, err)
+	}
+	if gracePeriod < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got %q", fieldName, value)
+	}
+	return gracePeriod, nil
+}
+
+// imagePullFailures returns an error naming the image and the pull error for
+// every container of the deployment's pods that has not been able to pull its
+// image since the pod was created more than gracePeriod ago
+func (c *oauthServerDeploymentSyncer) imagePullFailures(deployment *appsv1.Deployment, gracePeriod time.Duration) ([]error, error) {
+	pods, err := c.podsLister.Pods(deployment.Namespace).List(labels.SelectorFromSet(deployment.Spec.Template.Labels))
+	if err != nil {
+		return nil, err
+	}
+	return podImagePullFailures(pods, gracePeriod, time.Now()), nil
+}
+
+func podImagePullFailures(pods []*corev1.Pod, gracePeriod time.Duration, now time.Time) []error {
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+
+	var errs []error
+	for _, pod := range pods {
+		if pod.CreationTimestamp.Add(gracePeriod).After(now) {
+			continue
+		}
+
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			waiting := status.State.Waiting
+			if waiting == nil || !imagePullFailureReasons.Has(waiting.Reason) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("pod %s/%s: container %q cannot pull image %q: %s: %s",
+				pod.Namespace, pod.Name, status.Name, status.Image, waiting.Reason, waiting.Message))
+		}
+	}
+	return errs
+}
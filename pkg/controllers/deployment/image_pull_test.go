@@ -0,0 +1,165 @@
+package deployment
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestImagePullFailures(t *testing.T) {
+	const image = "quay.io/openshift/oauth-server@sha256:0123"
+
+	newPod := func(name string, age time.Duration, statuses ...corev1.ContainerStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "openshift-authentication",
+				Labels:            map[string]string{"app": "oauth-openshift"},
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			},
+			Status: corev1.PodStatus{ContainerStatuses: statuses},
+		}
+	}
+	waiting := func(reason, message string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name:  "oauth-openshift",
+			Image: image,
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}},
+		}
+	}
+	running := corev1.ContainerStatus{
+		Name:  "oauth-openshift",
+		Image: image,
+		Ready: true,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+
+	for _, tt := range []struct {
+		name              string
+		pods              []*corev1.Pod
+		unsupportedConfig map[string]interface{}
+		expectedErrors    []string
+	}{
+		{
+			name: "running pods",
+			pods: []*corev1.Pod{newPod("oauth-openshift-a", time.Hour, running)},
+		},
+		{
+			name: "ImagePullBackOff",
+			pods: []*corev1.Pod{
+				newPod("oauth-openshift-a", time.Hour, running),
+				newPod("oauth-openshift-b", time.Hour, waiting("ImagePullBackOff", `Back-off pulling image "`+image+`"`)),
+			},
+			expectedErrors: []string{
+				`pod openshift-authentication/oauth-openshift-b: container "oauth-openshift" cannot pull image "` + image + `": ImagePullBackOff: Back-off pulling image "` + image + `"`,
+			},
+		},
+		{
+			name: "ErrImagePull",
+			pods: []*corev1.Pod{
+				newPod("oauth-openshift-a", time.Hour, waiting("ErrImagePull", "unauthorized: authentication required")),
+			},
+			expectedErrors: []string{
+				`pod openshift-authentication/oauth-openshift-a: container "oauth-openshift" cannot pull image "` + image + `": ErrImagePull: unauthorized: authentication required`,
+			},
+		},
+		{
+			name: "pull failure within the grace period",
+			pods: []*corev1.Pod{
+				newPod("oauth-openshift-a", time.Minute, waiting("ImagePullBackOff", "Back-off pulling image")),
+			},
+		},
+		{
+			name: "pull failure with the grace period disabled",
+			pods: []*corev1.Pod{
+				newPod("oauth-openshift-a", time.Minute, waiting("ImagePullBackOff", "Back-off pulling image")),
+			},
+			unsupportedConfig: map[string]interface{}{"deployment": map[string]interface{}{"imagePullFailureGracePeriod": "0s"}},
+			expectedErrors: []string{
+				`pod openshift-authentication/oauth-openshift-a: container "oauth-openshift" cannot pull image "` + image + `": ImagePullBackOff: Back-off pulling image`,
+			},
+		},
+		{
+			name: "waiting for another reason",
+			pods: []*corev1.Pod{
+				newPod("oauth-openshift-a", time.Hour, waiting("CrashLoopBackOff", "back-off 5m0s restarting failed container")),
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, pod := range tt.pods {
+				if err := indexer.Add(pod); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := &oauthServerDeploymentSyncer{podsLister: corev1listers.NewPodLister(indexer)}
+
+			gracePeriod, err := imagePullFailureGracePeriod(tt.unsupportedConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-authentication"}}
+			deployment.Spec.Template.Labels = map[string]string{"app": "oauth-openshift"}
+
+			errs, err := c.imagePullFailures(deployment, gracePeriod)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expectedErrors, "\n") {
+				t.Errorf("expected errors:\n%s\ngot:\n%s", strings.Join(tt.expectedErrors, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestImagePullFailureGracePeriod(t *testing.T) {
+	for _, tt := range []struct {
+		name                string
+		unsupportedConfig   map[string]interface{}
+		expectedGracePeriod time.Duration
+		expectErr           bool
+	}{
+		{
+			name:                "default",
+			expectedGracePeriod: 5 * time.Minute,
+		},
+		{
+			name:                "custom",
+			unsupportedConfig:   map[string]interface{}{"deployment": map[string]interface{}{"imagePullFailureGracePeriod": "15m"}},
+			expectedGracePeriod: 15 * time.Minute,
+		},
+		{
+			name:              "negative",
+			unsupportedConfig: map[string]interface{}{"deployment": map[string]interface{}{"imagePullFailureGracePeriod": "-1m"}},
+			expectErr:         true,
+		},
+		{
+			name:              "invalid",
+			unsupportedConfig: map[string]interface{}{"deployment": map[string]interface{}{"imagePullFailureGracePeriod": "soon"}},
+			expectErr:         true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			gracePeriod, err := imagePullFailureGracePeriod(tt.unsupportedConfig)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if gracePeriod != tt.expectedGracePeriod {
+				t.Errorf("expected grace period %s, got %s", tt.expectedGracePeriod, gracePeriod)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package deployment
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	supportedPullPolicies = sets.NewString(
+		string(corev1.PullAlways),
+		string(corev1.PullIfNotPresent),
+		string(corev1.PullNever),
+	)
+	supportedRestartPolicies = sets.NewString(
+		string(corev1.RestartPolicyAlways),
+		string(corev1.RestartPolicyOnFailure),
+		string(corev1.RestartPolicyNever),
+	)
+	supportedDNSPolicies = sets.NewString(
+		string(corev1.DNSClusterFirstWithHostNet),
+		string(corev1.DNSClusterFirst),
+		string(corev1.DNSDefault),
+		string(corev1.DNSNone),
+	)
+	supportedTerminationMessagePolicies = sets.NewString(
+		string(corev1.TerminationMessageReadFile),
+		string(corev1.TerminationMessageFallbackToLogsOnError),
+	)
+	supportedPortProtocols = sets.NewString(
+		string(corev1.ProtocolTCP),
+		string(corev1.ProtocolUDP),
+		string(corev1.ProtocolSCTP),
+	)
+)
+
+// validatePodSpec runs a lightweight validation of the rendered pod spec so
+// that malformed renders are caught before they are applied. It is not meant
+// to replace the validation of the API server, it only covers what the
+// rendering can get wrong: required names, references between volumes and
+// their mounts, mounts shadowing each other and enum values. Unset enum values
+// are defaulted by the API server and therefore valid.
+func validatePodSpec(podSpec *corev1.PodSpec) error {
+	fldPath := field.NewPath("spec", "template", "spec")
+	errs := field.ErrorList{}
+
+	if len(podSpec.RestartPolicy) > 0 && !supportedRestartPolicies.Has(string(podSpec.RestartPolicy)) {
+		errs = append(errs, field.NotSupported(fldPath.Child("restartPolicy"), podSpec.RestartPolicy, supportedRestartPolicies.List()))
+	}
+	if len(podSpec.DNSPolicy) > 0 && !supportedDNSPolicies.Has(string(podSpec.DNSPolicy)) {
+		errs = append(errs, field.NotSupported(fldPath.Child("dnsPolicy"), podSpec.DNSPolicy, supportedDNSPolicies.List()))
+	}
+
+	volumeNames := sets.NewString()
+	for i, volume := range podSpec.Volumes {
+		idxPath := fldPath.Child("volumes").Index(i)
+		switch {
+		case len(volume.Name) == 0:
+			errs = append(errs, field.Required(idxPath.Child("name"), ""))
+		case volumeNames.Has(volume.Name):
+			errs = append(errs, field.Duplicate(idxPath.Child("name"), volume.Name))
+		default:
+			volumeNames.Insert(volume.Name)
+		}
+	}
+
+	if len(podSpec.Containers) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("containers"), ""))
+	}
+	containerNames := sets.NewString()
+	for i := range podSpec.InitContainers {
+		errs = append(errs, validateContainer(&podSpec.InitContainers[i], fldPath.Child("initContainers").Index(i), containerNames, volumeNames)...)
+	}
+	for i := range podSpec.Containers {
+		errs = append(errs, validateContainer(&podSpec.Containers[i], fldPath.Child("containers").Index(i), containerNames, volumeNames)...)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid pod spec: %w", errs.ToAggregate())
+	}
+	return nil
+}
+
+func validateContainer(container *corev1.Container, fldPath *field.Path, containerNames, volumeNames sets.String) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch {
+	case len(container.Name) == 0:
+		errs = append(errs, field.Required(fldPath.Child("name"), ""))
+	case containerNames.Has(container.Name):
+		errs = append(errs, field.Duplicate(fldPath.Child("name"), container.Name))
+	default:
+		containerNames.Insert(container.Name)
+		for _, msg := range validation.IsDNS1123Label(container.Name) {
+			errs = append(errs, field.Invalid(fldPath.Child("name"), container.Name, msg))
+		}
+	}
+
+	if len(container.ImagePullPolicy) > 0 && !supportedPullPolicies.Has(string(container.ImagePullPolicy)) {
+		errs = append(errs, field.NotSupported(fldPath.Child("imagePullPolicy"), container.ImagePullPolicy, supportedPullPolicies.List()))
+	}
+	if len(container.TerminationMessagePolicy) > 0 && !supportedTerminationMessagePolicies.Has(string(container.TerminationMessagePolicy)) {
+		errs = append(errs, field.NotSupported(fldPath.Child("terminationMessagePolicy"), container.TerminationMessagePolicy, supportedTerminationMessagePolicies.List()))
+	}
+
+	for i, port := range container.Ports {
+		idxPath := fldPath.Child("ports").Index(i)
+		for _, msg := range validation.IsValidPortNum(int(port.ContainerPort)) {
+			errs = append(errs, field.Invalid(idxPath.Child("containerPort"), port.ContainerPort, msg))
+		}
+		if len(port.Protocol) > 0 && !supportedPortProtocols.Has(string(port.Protocol)) {
+			errs = append(errs, field.NotSupported(idxPath.Child("protocol"), port.Protocol, supportedPortProtocols.List()))
+		}
+	}
+
+	// volumes mounted at the same path shadow each other. Only mounts at the
+	// same path once cleaned are caught, volumes mounted below the mount path
+	// of another volume are a valid way to combine them.
+	mountPaths := map[string]string{}
+	for i, mount := range container.VolumeMounts {
+		idxPath := fldPath.Child("volumeMounts").Index(i)
+		if len(mount.MountPath) == 0 {
+			errs = append(errs, field.Required(idxPath.Child("mountPath"), ""))
+		} else if mountedVolume, ok := mountPaths[path.Clean(mount.MountPath)]; ok {
+			errs = append(errs, field.Invalid(idxPath.Child("mountPath"), mount.MountPath, fmt.Sprintf("volume %q is already mounted at this path", mountedVolume)))
+		} else {
+			mountPaths[path.Clean(mount.MountPath)] = mount.Name
+		}
+		if !volumeNames.Has(mount.Name) {
+			errs = append(errs, field.NotFound(idxPath.Child("name"), mount.Name))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,127 @@
+package deployment
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidatePodSpec(t *testing.T) {
+	validPodSpec := func() *corev1.PodSpec {
+		return &corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyAlways,
+			Volumes: []corev1.Volume{
+				{Name: "audit-dir"},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:                     oauthServerContainerName,
+					ImagePullPolicy:          corev1.PullIfNotPresent,
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					Ports: []corev1.ContainerPort{
+						{Name: "https", ContainerPort: 6443, Protocol: corev1.ProtocolTCP},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "audit-dir", MountPath: "/var/log/oauth-server"},
+					},
+				},
+			},
+		}
+	}
+
+	for _, tt := range []struct {
+		name          string
+		modify        func(*corev1.PodSpec)
+		expectedError string
+	}{
+		{
+			name:   "valid",
+			modify: func(*corev1.PodSpec) {},
+		},
+		{
+			name: "unset enum values",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.RestartPolicy = ""
+				podSpec.Containers[0].ImagePullPolicy = ""
+			},
+		},
+		{
+			name: "invalid image pull policy",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.Containers[0].ImagePullPolicy = "Sometimes"
+			},
+			expectedError: `spec.template.spec.containers[0].imagePullPolicy: Unsupported value: "Sometimes"`,
+		},
+		{
+			name: "invalid restart policy",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.RestartPolicy = "Always "
+			},
+			expectedError: `spec.template.spec.restartPolicy: Unsupported value: "Always "`,
+		},
+		{
+			name: "invalid port protocol",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.Containers[0].Ports[0].Protocol = "tcp"
+			},
+			expectedError: `spec.template.spec.containers[0].ports[0].protocol: Unsupported value: "tcp"`,
+		},
+		{
+			name: "mount without volume",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.Volumes = nil
+			},
+			expectedError: `spec.template.spec.containers[0].volumeMounts[0].name: Not found: "audit-dir"`,
+		},
+		{
+			name: "colliding mount paths",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{Name: "idp-secret"})
+				podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts,
+					corev1.VolumeMount{Name: "idp-secret", MountPath: "/var/log/oauth-server/"},
+				)
+			},
+			expectedError: `spec.template.spec.containers[0].volumeMounts[1].mountPath: Invalid value: "/var/log/oauth-server/": volume "audit-dir" is already mounted at this path`,
+		},
+		{
+			name: "nested mount paths",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{Name: "idp-secret"})
+				podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts,
+					corev1.VolumeMount{Name: "idp-secret", MountPath: "/var/log/oauth-server/idp"},
+				)
+			},
+		},
+		{
+			name: "duplicate container names",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.Containers = append(podSpec.Containers, *podSpec.Containers[0].DeepCopy())
+			},
+			expectedError: `spec.template.spec.containers[1].name: Duplicate value: "oauth-openshift"`,
+		},
+		{
+			name: "no containers",
+			modify: func(podSpec *corev1.PodSpec) {
+				podSpec.Containers = nil
+			},
+			expectedError: "spec.template.spec.containers: Required value",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			podSpec := validPodSpec()
+			tt.modify(podSpec)
+
+			err := validatePodSpec(podSpec)
+			if len(tt.expectedError) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("expected error containing %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package deployment
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+)
+
+const (
+	// resyncIntervalEnv sets the interval in which the oauth-server deployment is
+	// reconciled regardless of events, as a Go duration. "0" turns the periodic
+	// reconcile off.
+	resyncIntervalEnv = "OAUTH_SERVER_DEPLOYMENT_RESYNC_INTERVAL"
+
+	// defaultResyncInterval is a safety net for drift that no informer notices,
+	// the deployment is otherwise reconciled on events
+	defaultResyncInterval = 5 * time.Minute
+)
+
+// resyncIntervalFromEnv returns the resync interval of the deployment
+// controller as configured in the environment of the operator
+func resyncIntervalFromEnv() time.Duration {
+	value, ok := os.LookupEnv(resyncIntervalEnv)
+	if !ok || len(value) == 0 {
+		return defaultResyncInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		klog.Warningf("invalid %s %q, using the default of %s", resyncIntervalEnv, value, defaultResyncInterval)
+		return defaultResyncInterval
+	}
+	return interval
+}
+
+// resyncInformer is a factory.Informer that notifies its handlers of an update
+// in a fixed interval. The workload controller only syncs on informer events,
+// this makes it resync periodically.
+type resyncInformer struct {
+	interval time.Duration
+
+	lock     sync.Mutex
+	handlers []cache.ResourceEventHandler
+}
+
+var _ factory.Informer = &resyncInformer{}
+
+func (i *resyncInformer) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.handlers = append(i.handlers, handler)
+	return i, nil
+}
+
+func (i *resyncInformer) HasSynced() bool {
+	return true
+}
+
+// run notifies the handlers every interval until the context is done
+func (i *resyncInformer) run(ctx context.Context) {
+	if i.interval <= 0 {
+		return
+	}
+
+	// the factory event handlers only accept runtime objects
+	obj := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "resync"}}
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		i.lock.Lock()
+		handlers := append([]cache.ResourceEventHandler{}, i.handlers...)
+		i.lock.Unlock()
+		for _, handler := range handlers {
+			handler.OnUpdate(obj, obj)
+		}
+	}
+}
+
+// resyncController runs the resync informer along with the controller
+type resyncController struct {
+	factory.Controller
+	resync *resyncInformer
+}
+
+func (c *resyncController) Run(ctx context.Context, workers int) {
+	go c.resync.run(ctx)
+	c.Controller.Run(ctx, workers)
+}
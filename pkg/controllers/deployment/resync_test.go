@@ -0,0 +1,78 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestResyncIntervalFromEnv(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{
+			name:     "default",
+			expected: defaultResyncInterval,
+		},
+		{
+			name:     "configured",
+			value:    "30m",
+			expected: 30 * time.Minute,
+		},
+		{
+			name:     "disabled",
+			value:    "0",
+			expected: 0,
+		},
+		{
+			name:     "invalid",
+			value:    "often",
+			expected: defaultResyncInterval,
+		},
+		{
+			name:     "negative",
+			value:    "-1m",
+			expected: defaultResyncInterval,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(resyncIntervalEnv, tt.value)
+			if got := resyncIntervalFromEnv(); got != tt.expected {
+				t.Errorf("expected resync interval %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestResyncInformer(t *testing.T) {
+	const interval = 20 * time.Millisecond
+
+	updates := make(chan time.Time, 10)
+	informer := &resyncInformer{interval: interval}
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, _ interface{}) { updates <- time.Now() },
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := time.Now()
+	go informer.run(ctx)
+
+	var last time.Time
+	for i := 0; i < 3; i++ {
+		select {
+		case last = <-updates:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected update %d within the resync interval", i)
+		}
+	}
+	if elapsed := last.Sub(started); elapsed < 3*interval {
+		t.Errorf("expected 3 updates to take at least %s, took %s", 3*interval, elapsed)
+	}
+}
@@ -0,0 +1,193 @@
+package deployment
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
+)
+
+// observedConfigChange classifies what changed in the observed config of the
+// oauth-server so that rollouts can be attributed to it
+type observedConfigChange string
+
+const (
+	// auditConfigChange covers the audit-* server arguments and the audit policy
+	auditConfigChange observedConfigChange = "audit"
+	// serverArgumentsChange covers the server arguments not related to auditing
+	serverArgumentsChange observedConfigChange = "serverArguments"
+	// identityProvidersChange covers the identity providers and the resources
+	// they reference
+	identityProvidersChange observedConfigChange = "identityProviders"
+	// otherConfigChange covers the rest of the observed config
+	otherConfigChange observedConfigChange = "other"
+)
+
+var (
+	observedServerArgumentsPath = []string{configobservation.OAuthServerConfigPrefix, "serverArguments"}
+
+	// observedConfigChangePaths maps the subsections of the observed config
+	// to the change they are classified as, apart from the server arguments
+	// which are split into audit and other arguments
+	observedConfigChangePaths = map[observedConfigChange][][]string{
+		auditConfigChange: {
+			{configobservation.OAuthServerConfigPrefix, "auditPolicy"},
+		},
+		identityProvidersChange: {
+			{configobservation.OAuthServerConfigPrefix, "oauthConfig", "identityProviders"},
+			{configobservation.OAuthServerConfigPrefix, "volumesToMount", "identityProviders"},
+		},
+	}
+)
+
+// observedConfigChanges diffs two observed configs of the operator and returns
+// the sorted classes of the changes between them
+func observedConfigChanges(oldObservedConfig, newObservedConfig []byte) ([]observedConfigChange, error) {
+	changes := map[observedConfigChange]bool{}
+
+	oldArgs, err := unstructuredSubsection(oldObservedConfig, observedServerArgumentsPath)
+	if err != nil {
+		return nil, err
+	}
+	newArgs, err := unstructuredSubsection(newObservedConfig, observedServerArgumentsPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range changedKeys(oldArgs, newArgs) {
+		if strings.HasPrefix(name, "audit-") {
+			changes[auditConfigChange] = true
+		} else {
+			changes[serverArgumentsChange] = true
+		}
+	}
+
+	classifiedPaths := [][]string{observedServerArgumentsPath}
+	for change, paths := range observedConfigChangePaths {
+		for _, fieldPath := range paths {
+			classifiedPaths = append(classifiedPaths, fieldPath)
+
+			oldSection, err := unstructuredSubsection(oldObservedConfig, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			newSection, err := unstructuredSubsection(newObservedConfig, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			if !equality.Semantic.DeepEqual(oldSection, newSection) {
+				changes[change] = true
+			}
+		}
+	}
+
+	oldRest, err := unclassifiedConfig(oldObservedConfig, classifiedPaths)
+	if err != nil {
+		return nil, err
+	}
+	newRest, err := unclassifiedConfig(newObservedConfig, classifiedPaths)
+	if err != nil {
+		return nil, err
+	}
+	if !equality.Semantic.DeepEqual(oldRest, newRest) {
+		changes[otherConfigChange] = true
+	}
+
+	ret := make([]observedConfigChange, 0, len(changes))
+	for change := range changes {
+		ret = append(ret, change)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret, nil
+}
+
+// recordRolloutReason emits an event attributing the update of the deployment
+// to the changes of the observed config since the previous update, if any
+func recordRolloutReason(recorder events.Recorder, oldObservedConfig, newObservedConfig []byte) error {
+	if oldObservedConfig == nil {
+		return nil
+	}
+
+	changes, err := observedConfigChanges(oldObservedConfig, newObservedConfig)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	reasons := make([]string, 0, len(changes))
+	for _, change := range changes {
+		reasons = append(reasons, string(change))
+	}
+	recorder.Eventf("OAuthServerDeploymentUpdated", "the deployment of the oauth-server was updated due to observed config changes of: %s", strings.Join(reasons, ", "))
+	return nil
+}
+
+// unstructuredSubsection returns the subsection of the observed config at
+// fieldPath, nil if it is not set
+func unstructuredSubsection(observedConfig []byte, fieldPath []string) (interface{}, error) {
+	raw, err := common.UnstructuredConfigFrom(observedConfig, fieldPath...)
+	if err != nil {
+		return nil, err
+	}
+
+	var section interface{}
+	if err := json.Unmarshal(raw, &section); err != nil {
+		return nil, err
+	}
+	return section, nil
+}
+
+// unclassifiedConfig returns the oauth-server observed config without the
+// given classified paths
+func unclassifiedConfig(observedConfig []byte, classifiedPaths [][]string) (interface{}, error) {
+	section, err := unstructuredSubsection(observedConfig, []string{configobservation.OAuthServerConfigPrefix})
+	if err != nil {
+		return nil, err
+	}
+	config, ok := section.(map[string]interface{})
+	if !ok {
+		return section, nil
+	}
+
+	for _, fieldPath := range classifiedPaths {
+		removeField(config, fieldPath[1:])
+	}
+	return config, nil
+}
+
+func removeField(config map[string]interface{}, fieldPath []string) {
+	if len(fieldPath) == 1 {
+		delete(config, fieldPath[0])
+		return
+	}
+	if nested, ok := config[fieldPath[0]].(map[string]interface{}); ok {
+		removeField(nested, fieldPath[1:])
+		if len(nested) == 0 {
+			delete(config, fieldPath[0])
+		}
+	}
+}
+
+// changedKeys returns the keys whose values differ between two objects, or
+// all keys of either object if one of them is not an object
+func changedKeys(oldSection, newSection interface{}) []string {
+	oldMap, _ := oldSection.(map[string]interface{})
+	newMap, _ := newSection.(map[string]interface{})
+
+	changed := []string{}
+	for key, oldValue := range oldMap {
+		if newValue, ok := newMap[key]; !ok || !equality.Semantic.DeepEqual(oldValue, newValue) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range newMap {
+		if _, ok := oldMap[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
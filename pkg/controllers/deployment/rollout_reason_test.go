@@ -0,0 +1,161 @@
+package deployment
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+)
+
+func TestObservedConfigChanges(t *testing.T) {
+	const baseConfig = `{"oauthServer":{
+  "serverArguments":{"audit-log-format":["json"],"audit-policy-file":["/var/run/configmaps/audit/audit.yaml"]},
+  "auditPolicy":{"profile":"WriteRequestBodies"},
+  "oauthConfig":{"identityProviders":[{"name":"htpasswd"}],"loginURL":"https://api.example.com:6443"},
+  "volumesToMount":{"identityProviders":"{\"v4-0-config-user-idp-0-file-data\":{\"name\":\"htpasswd\"}}"}
+}}`
+
+	for _, tt := range []struct {
+		name            string
+		oldConfig       string
+		newConfig       string
+		expectedChanges []observedConfigChange
+	}{
+		{
+			name:            "no change",
+			oldConfig:       baseConfig,
+			newConfig:       baseConfig,
+			expectedChanges: []observedConfigChange{},
+		},
+		{
+			name:      "audit server argument",
+			oldConfig: baseConfig,
+			newConfig: `{"oauthServer":{
+  "serverArguments":{"audit-log-format":["legacy"],"audit-policy-file":["/var/run/configmaps/audit/audit.yaml"]},
+  "auditPolicy":{"profile":"WriteRequestBodies"},
+  "oauthConfig":{"identityProviders":[{"name":"htpasswd"}],"loginURL":"https://api.example.com:6443"},
+  "volumesToMount":{"identityProviders":"{\"v4-0-config-user-idp-0-file-data\":{\"name\":\"htpasswd\"}}"}
+}}`,
+			expectedChanges: []observedConfigChange{auditConfigChange},
+		},
+		{
+			name:      "audit policy",
+			oldConfig: baseConfig,
+			newConfig: `{"oauthServer":{
+  "serverArguments":{"audit-log-format":["json"],"audit-policy-file":["/var/run/configmaps/audit/audit.yaml"]},
+  "oauthConfig":{"identityProviders":[{"name":"htpasswd"}],"loginURL":"https://api.example.com:6443"},
+  "volumesToMount":{"identityProviders":"{\"v4-0-config-user-idp-0-file-data\":{\"name\":\"htpasswd\"}}"}
+}}`,
+			expectedChanges: []observedConfigChange{auditConfigChange},
+		},
+		{
+			name:      "other server argument",
+			oldConfig: baseConfig,
+			newConfig: `{"oauthServer":{
+  "serverArguments":{"audit-log-format":["json"],"audit-policy-file":["/var/run/configmaps/audit/audit.yaml"],"vmodule":["osinserver=4"]},
+  "auditPolicy":{"profile":"WriteRequestBodies"},
+  "oauthConfig":{"identityProviders":[{"name":"htpasswd"}],"loginURL":"https://api.example.com:6443"},
+  "volumesToMount":{"identityProviders":"{\"v4-0-config-user-idp-0-file-data\":{\"name\":\"htpasswd\"}}"}
+}}`,
+			expectedChanges: []observedConfigChange{serverArgumentsChange},
+		},
+		{
+			name:      "identity provider",
+			oldConfig: baseConfig,
+			newConfig: `{"oauthServer":{
+  "serverArguments":{"audit-log-format":["json"],"audit-policy-file":["/var/run/configmaps/audit/audit.yaml"]},
+  "auditPolicy":{"profile":"WriteRequestBodies"},
+  "oauthConfig":{"identityProviders":[{"name":"ldap"}],"loginURL":"https://api.example.com:6443"},
+  "volumesToMount":{"identityProviders":"{\"v4-0-config-user-idp-0-file-data\":{\"name\":\"htpasswd\"}}"}
+}}`,
+			expectedChanges: []observedConfigChange{identityProvidersChange},
+		},
+		{
+			name:      "identity provider references",
+			oldConfig: baseConfig,
+			newConfig: `{"oauthServer":{
+  "serverArguments":{"audit-log-format":["json"],"audit-policy-file":["/var/run/configmaps/audit/audit.yaml"]},
+  "auditPolicy":{"profile":"WriteRequestBodies"},
+  "oauthConfig":{"identityProviders":[{"name":"htpasswd"}],"loginURL":"https://api.example.com:6443"},
+  "volumesToMount":{"identityProviders":"{\"v4-0-config-user-idp-0-file-data\":{\"name\":\"htpasswd-new\"}}"}
+}}`,
+			expectedChanges: []observedConfigChange{identityProvidersChange},
+		},
+		{
+			name:      "other config",
+			oldConfig: baseConfig,
+			newConfig: `{"oauthServer":{
+  "serverArguments":{"audit-log-format":["json"],"audit-policy-file":["/var/run/configmaps/audit/audit.yaml"]},
+  "auditPolicy":{"profile":"WriteRequestBodies"},
+  "oauthConfig":{"identityProviders":[{"name":"htpasswd"}],"loginURL":"https://api.new.example.com:6443"},
+  "volumesToMount":{"identityProviders":"{\"v4-0-config-user-idp-0-file-data\":{\"name\":\"htpasswd\"}}"}
+}}`,
+			expectedChanges: []observedConfigChange{otherConfigChange},
+		},
+		{
+			name:      "several changes",
+			oldConfig: baseConfig,
+			newConfig: `{"oauthServer":{
+  "serverArguments":{"audit-log-format":["json"]},
+  "oauthConfig":{"loginURL":"https://api.example.com:6443"}
+}}`,
+			expectedChanges: []observedConfigChange{auditConfigChange, identityProvidersChange},
+		},
+		{
+			name:            "from nothing",
+			oldConfig:       `{}`,
+			newConfig:       baseConfig,
+			expectedChanges: []observedConfigChange{auditConfigChange, identityProvidersChange, otherConfigChange},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := observedConfigChanges([]byte(tt.oldConfig), []byte(tt.newConfig))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.expectedChanges, changes) {
+				t.Errorf("expected changes %v, got %v", tt.expectedChanges, changes)
+			}
+		})
+	}
+}
+
+func TestRecordRolloutReason(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		oldConfig       []byte
+		newConfig       []byte
+		expectedMessage string
+	}{
+		{
+			name:      "first update",
+			newConfig: []byte(`{"oauthServer":{"serverArguments":{"audit-log-format":["json"]}}}`),
+		},
+		{
+			name:      "unchanged observed config",
+			oldConfig: []byte(`{"oauthServer":{"serverArguments":{"audit-log-format":["json"]}}}`),
+			newConfig: []byte(`{"oauthServer":{"serverArguments":{"audit-log-format":["json"]}}}`),
+		},
+		{
+			name:            "changed observed config",
+			oldConfig:       []byte(`{"oauthServer":{"serverArguments":{"audit-log-format":["json"]}}}`),
+			newConfig:       []byte(`{"oauthServer":{"serverArguments":{"audit-log-format":["legacy"],"vmodule":["osinserver=4"]}}}`),
+			expectedMessage: "the deployment of the oauth-server was updated due to observed config changes of: audit, serverArguments",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := events.NewInMemoryRecorder(t.Name())
+			if err := recordRolloutReason(recorder, tt.oldConfig, tt.newConfig); err != nil {
+				t.Fatal(err)
+			}
+
+			recorded := recorder.Events()
+			switch {
+			case len(tt.expectedMessage) == 0 && len(recorded) > 0:
+				t.Errorf("expected no events, got %v", recorded)
+			case len(tt.expectedMessage) > 0 && (len(recorded) != 1 || recorded[0].Message != tt.expectedMessage):
+				t.Errorf("expected an event with message %q, got %v", tt.expectedMessage, recorded)
+			}
+		})
+	}
+}
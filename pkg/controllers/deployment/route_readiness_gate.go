@@ -0,0 +1,121 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// routeAdmittedConditionType is the pod condition behind the readiness gate
+// of the oauth-server pods, true once the oauth-openshift route is admitted
+const routeAdmittedConditionType corev1.PodConditionType = "authentication.operator.openshift.io/RouteAdmitted"
+
+var routeAdmissionReadinessGatePath = []string{"deployment", "routeAdmissionReadinessGate"}
+
+// setRouteAdmissionReadinessGate adds the route admission readiness gate to the
+// pods when deployment.routeAdmissionReadinessGate of unsupportedConfigOverrides.operator
+// is set to true. The pods then do not become ready for traffic before the
+// operator reports the oauth-openshift route as admitted on them.
+func setRouteAdmissionReadinessGate(spec *corev1.PodSpec, unsupportedConfig map[string]interface{}) error {
+	enabled, _, err := unstructured.NestedBool(unsupportedConfig, routeAdmissionReadinessGatePath...)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", strings.Join(routeAdmissionReadinessGatePath, "."), err)
+	}
+	if !enabled || hasRouteAdmissionReadinessGate(spec) {
+		return nil
+	}
+
+	spec.ReadinessGates = append(spec.ReadinessGates, corev1.PodReadinessGate{ConditionType: routeAdmittedConditionType})
+	return nil
+}
+
+func hasRouteAdmissionReadinessGate(spec *corev1.PodSpec) bool {
+	for _, gate := range spec.ReadinessGates {
+		if gate.ConditionType == routeAdmittedConditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// routeNotAdmitted is called when the precondition of the workload fails for
+// the oauth-openshift route not being admitted, Sync does not run then. The
+// route admission condition of the pods of the current deployment is set to
+// False from here and the precondition error is returned.
+func (c *oauthServerDeploymentSyncer) routeNotAdmitted(ctx context.Context, reason string, preconditionErr error) error {
+	deployment, err := c.deploymentLister.Deployments("openshift-authentication").Get("oauth-openshift")
+	if errors.IsNotFound(err) {
+		return preconditionErr
+	} else if err != nil {
+		return fmt.Errorf("%w, unable to get the oauth-server deployment: %v", preconditionErr, err)
+	}
+
+	if err := c.syncRouteAdmittedCondition(ctx, deployment, corev1.ConditionFalse, reason, preconditionErr.Error()); err != nil {
+		return fmt.Errorf("%w, %v", preconditionErr, err)
+	}
+	return preconditionErr
+}
+
+// syncRouteAdmittedCondition sets the route admission condition of the pods of
+// the deployment. Pods of a deployment without the readiness gate are left
+// alone.
+func (c *oauthServerDeploymentSyncer) syncRouteAdmittedCondition(ctx context.Context, deployment *appsv1.Deployment, status corev1.ConditionStatus, reason, message string) error {
+	if !hasRouteAdmissionReadinessGate(&deployment.Spec.Template.Spec) {
+		return nil
+	}
+
+	pods, err := c.podsLister.Pods(deployment.Namespace).List(labels.SelectorFromSet(deployment.Spec.Template.Labels))
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, pod := range pods {
+		pod = pod.DeepCopy()
+		if !setPodCondition(pod, corev1.PodCondition{
+			Type:    routeAdmittedConditionType,
+			Status:  status,
+			Reason:  reason,
+			Message: message,
+		}) {
+			continue
+		}
+		if _, err := c.pods.Pods(pod.Namespace).UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil {
+			errs = append(errs, fmt.Sprintf("pod %s/%s: %v", pod.Namespace, pod.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to set the %s condition of the oauth-server pods: %s", routeAdmittedConditionType, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// setPodCondition sets the condition on the pod and returns whether its status,
+// reason or message changed. The transition time only moves with the status.
+func setPodCondition(pod *corev1.Pod, condition corev1.PodCondition) bool {
+	for i := range pod.Status.Conditions {
+		existing := &pod.Status.Conditions[i]
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+			return false
+		}
+		if existing.Status != condition.Status {
+			existing.LastTransitionTime = metav1.Now()
+		}
+		existing.Status, existing.Reason, existing.Message = condition.Status, condition.Reason, condition.Message
+		return true
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+	pod.Status.Conditions = append(pod.Status.Conditions, condition)
+	return true
+}
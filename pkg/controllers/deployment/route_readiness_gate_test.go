@@ -0,0 +1,222 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	routev1 "github.com/openshift/api/route/v1"
+	routev1listers "github.com/openshift/client-go/route/listers/route/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+)
+
+func TestSetRouteAdmissionReadinessGate(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig map[string]interface{}
+		existingGates     []corev1.PodReadinessGate
+		expectedGates     []corev1.PodReadinessGate
+		expectError       bool
+	}{
+		{
+			name: "not configured",
+		},
+		{
+			name:              "disabled",
+			unsupportedConfig: map[string]interface{}{"deployment": map[string]interface{}{"routeAdmissionReadinessGate": false}},
+		},
+		{
+			name:              "enabled",
+			unsupportedConfig: map[string]interface{}{"deployment": map[string]interface{}{"routeAdmissionReadinessGate": true}},
+			expectedGates:     []corev1.PodReadinessGate{{ConditionType: routeAdmittedConditionType}},
+		},
+		{
+			name:              "enabled with the gate already present",
+			unsupportedConfig: map[string]interface{}{"deployment": map[string]interface{}{"routeAdmissionReadinessGate": true}},
+			existingGates:     []corev1.PodReadinessGate{{ConditionType: routeAdmittedConditionType}},
+			expectedGates:     []corev1.PodReadinessGate{{ConditionType: routeAdmittedConditionType}},
+		},
+		{
+			name:              "not a boolean",
+			unsupportedConfig: map[string]interface{}{"deployment": map[string]interface{}{"routeAdmissionReadinessGate": "yes"}},
+			expectError:       true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &corev1.PodSpec{ReadinessGates: tt.existingGates}
+			err := setRouteAdmissionReadinessGate(spec, tt.unsupportedConfig)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected error %t, got %v", tt.expectError, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(spec.ReadinessGates) != len(tt.expectedGates) {
+				t.Fatalf("expected readiness gates %v, got %v", tt.expectedGates, spec.ReadinessGates)
+			}
+			for i := range tt.expectedGates {
+				if spec.ReadinessGates[i] != tt.expectedGates[i] {
+					t.Errorf("expected readiness gates %v, got %v", tt.expectedGates, spec.ReadinessGates)
+				}
+			}
+		})
+	}
+}
+
+func TestPreconditionFulfilledRouteAdmission(t *testing.T) {
+	admittedCondition := corev1.PodCondition{Type: routeAdmittedConditionType, Status: corev1.ConditionTrue, Reason: "RouteAdmitted"}
+
+	for _, tt := range []struct {
+		name              string
+		withoutGate       bool
+		withoutDeployment bool
+		route             *routev1.Route
+		podConditions     []corev1.PodCondition
+		expectFulfilled   bool
+		expectedStatus    corev1.ConditionStatus
+		expectedReason    string
+	}{
+		{
+			name:           "route missing",
+			podConditions:  []corev1.PodCondition{admittedCondition},
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: "RouteNotFound",
+		},
+		{
+			name:           "route not admitted",
+			route:          newRouteAdmissionTestRoute(corev1.ConditionFalse),
+			expectedStatus: corev1.ConditionFalse,
+			expectedReason: "RouteNotAdmitted",
+		},
+		{
+			name:            "route admitted",
+			route:           newRouteAdmissionTestRoute(corev1.ConditionTrue),
+			expectFulfilled: true,
+		},
+		{
+			name:        "deployment without the readiness gate",
+			withoutGate: true,
+		},
+		{
+			name:              "no deployment yet",
+			withoutDeployment: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := newRouteAdmissionTestPod(tt.podConditions...)
+			kubeClient := fake.NewSimpleClientset(pod)
+
+			var routes []runtime.Object
+			if tt.route != nil {
+				routes = append(routes, tt.route)
+			}
+
+			c := &oauthServerDeploymentSyncer{
+				pods:             kubeClient.CoreV1(),
+				podsLister:       corev1listers.NewPodLister(newTestIndexer(t, pod)),
+				routeLister:      routev1listers.NewRouteLister(newTestIndexer(t, routes...)),
+				deploymentLister: appsv1listers.NewDeploymentLister(newTestIndexer(t)),
+			}
+			if !tt.withoutDeployment {
+				deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "oauth-openshift", Namespace: "openshift-authentication"}}
+				deployment.Spec.Template.Labels = map[string]string{"app": "oauth-openshift"}
+				if !tt.withoutGate {
+					deployment.Spec.Template.Spec.ReadinessGates = []corev1.PodReadinessGate{{ConditionType: routeAdmittedConditionType}}
+				}
+				c.deploymentLister = appsv1listers.NewDeploymentLister(newTestIndexer(t, deployment))
+			}
+
+			fulfilled, err := c.PreconditionFulfilled(context.TODO())
+			if fulfilled != tt.expectFulfilled {
+				t.Fatalf("expected the precondition to be fulfilled: %t, got %t (%v)", tt.expectFulfilled, fulfilled, err)
+			}
+			if fulfilled != (err == nil) {
+				t.Fatalf("expected an error only for an unfulfilled precondition, got %v", err)
+			}
+
+			condition := getRouteAdmittedCondition(t, kubeClient)
+			if len(tt.expectedStatus) == 0 {
+				if len(kubeClient.Actions()) != 1 {
+					t.Errorf("expected the pods to be left alone, got actions %v", kubeClient.Actions()[1:])
+				}
+				return
+			}
+			if condition == nil {
+				t.Fatalf("expected the %s condition to be set", routeAdmittedConditionType)
+			}
+			if condition.Status != tt.expectedStatus || condition.Reason != tt.expectedReason || condition.Message != err.Error() {
+				t.Errorf("expected condition %s/%s with message %q, got %s/%s with message %q", tt.expectedStatus, tt.expectedReason, err.Error(), condition.Status, condition.Reason, condition.Message)
+			}
+		})
+	}
+}
+
+func TestSyncRouteAdmitted(t *testing.T) {
+	pod := newRouteAdmissionTestPod(corev1.PodCondition{Type: routeAdmittedConditionType, Status: corev1.ConditionFalse, Reason: "RouteNotAdmitted"})
+	operatorConfig := newTestOperatorConfig(`{"operator":{"deployment":{"routeAdmissionReadinessGate":true}}}`)
+	operatorConfig.Name = "cluster"
+
+	c, kubeClient := newTestSyncer(t, operatorConfig, pod, newRouteAdmissionTestRoute(corev1.ConditionTrue))
+
+	if fulfilled, err := c.PreconditionFulfilled(context.TODO()); !fulfilled || err != nil {
+		t.Fatalf("expected the precondition to be fulfilled, got %t: %v", fulfilled, err)
+	}
+
+	deployment, _, errs := c.Sync(context.TODO(), factory.NewSyncContext(t.Name(), events.NewInMemoryRecorder(t.Name())))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if !hasRouteAdmissionReadinessGate(&deployment.Spec.Template.Spec) {
+		t.Fatalf("expected the deployment to have the %s readiness gate", routeAdmittedConditionType)
+	}
+
+	condition := getRouteAdmittedCondition(t, kubeClient)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != "RouteAdmitted" {
+		t.Errorf("expected the %s condition to be True, got %v", routeAdmittedConditionType, condition)
+	}
+}
+
+func newRouteAdmissionTestPod(conditions ...corev1.PodCondition) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "oauth-openshift-a",
+			Namespace: "openshift-authentication",
+			Labels:    map[string]string{"app": "oauth-openshift"},
+		},
+		Status: corev1.PodStatus{Conditions: conditions},
+	}
+}
+
+func newRouteAdmissionTestRoute(admitted corev1.ConditionStatus) *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: "oauth-openshift", Namespace: "openshift-authentication"},
+		Spec:       routev1.RouteSpec{Host: "oauth-openshift.apps.example.com"},
+		Status: routev1.RouteStatus{
+			Ingress: []routev1.RouteIngress{{
+				Host:       "oauth-openshift.apps.example.com",
+				Conditions: []routev1.RouteIngressCondition{{Type: routev1.RouteAdmitted, Status: admitted}},
+			}},
+		},
+	}
+}
+
+func getRouteAdmittedCondition(t *testing.T, kubeClient *fake.Clientset) *corev1.PodCondition {
+	pod, err := kubeClient.CoreV1().Pods("openshift-authentication").Get(context.TODO(), "oauth-openshift-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == routeAdmittedConditionType {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package deployment
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common/arguments"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation"
+	observeoauth "github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/oauth"
+)
+
+var (
+	skipServerArgumentsValidationPath = []string{
+		"deployment", "skipServerArgumentsValidation",
+	}
+
+	// knownOAuthServerFlags are the flags accepted by `oauth-server osinserver`,
+	// i.e. the generic apiserver audit options on top of the config and logging flags.
+	knownOAuthServerFlags = sets.NewString(
+		"config",
+		"v",
+		"vmodule",
+
+		"audit-log-batch-buffer-size",
+		"audit-log-batch-max-size",
+		"audit-log-batch-max-wait",
+		"audit-log-batch-throttle-burst",
+		"audit-log-batch-throttle-enable",
+		"audit-log-batch-throttle-qps",
+		"audit-log-compress",
+		"audit-log-format",
+		"audit-log-maxage",
+		"audit-log-maxbackup",
+		"audit-log-maxsize",
+		"audit-log-mode",
+		"audit-log-path",
+		"audit-log-truncate-enabled",
+		"audit-log-truncate-max-batch-size",
+		"audit-log-truncate-max-event-size",
+		"audit-log-version",
+		"audit-policy-file",
+		"audit-webhook-batch-buffer-size",
+		"audit-webhook-batch-initial-backoff",
+		"audit-webhook-batch-max-size",
+		"audit-webhook-batch-max-wait",
+		"audit-webhook-batch-throttle-burst",
+		"audit-webhook-batch-throttle-enable",
+		"audit-webhook-batch-throttle-qps",
+		"audit-webhook-config-file",
+		"audit-webhook-initial-backoff",
+		"audit-webhook-mode",
+		"audit-webhook-truncate-enabled",
+		"audit-webhook-truncate-max-batch-size",
+		"audit-webhook-truncate-max-event-size",
+		"audit-webhook-version",
+	)
+
+	// fileServerArguments are the oauth-server flags whose values are paths of
+	// files that have to be provided by a volume mounted to the container
+	fileServerArguments = []string{
+		"audit-log-path",
+		"audit-policy-file",
+		"audit-webhook-config-file",
+	}
+
+	// auditWebhookThrottleArguments throttle the batches sent by the webhook
+	// audit backend, which is only enabled by audit-webhook-config-file
+	auditWebhookThrottleArguments = []string{
+		"audit-webhook-batch-throttle-qps",
+		"audit-webhook-batch-throttle-burst",
+	}
+)
+
+// unknownServerArguments returns the sorted names of the server arguments
+// that the oauth-server is not known to support.
+func unknownServerArguments(args arguments.ServerArguments) []string {
+	unknown := []string{}
+	for name := range args {
+		if !knownOAuthServerFlags.Has(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// serverArgumentWarnings returns the non-fatal problems of the server arguments
+// in the observed config: the arguments unknown to the oauth-server, or the
+// fact that they are not validated at all.
+func serverArgumentWarnings(operatorConfig *operatorv1.Authentication, unsupportedConfig map[string]interface{}) (*common.ConfigWarnings, error) {
+	warnings := &common.ConfigWarnings{}
+
+	skip, err := skipServerArgumentsValidation(unsupportedConfig)
+	if err != nil {
+		return nil, err
+	}
+	if skip {
+		warnings.Warnf("server arguments are not validated as %s is set", strings.Join(skipServerArgumentsValidationPath, "."))
+		return warnings, nil
+	}
+
+	observedConfig, err := common.UnstructuredConfigFrom(operatorConfig.Spec.ObservedConfig.Raw, configobservation.OAuthServerConfigPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the operatorconfig prefix %q: %w", configobservation.OAuthServerConfigPrefix, err)
+	}
+	argsRaw, err := getOAuthServerArgumentsRaw(observedConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve server arguments from observed config: %w", err)
+	}
+	args, err := arguments.Parse(argsRaw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse raw server arguments: %w", err)
+	}
+
+	for _, name := range unknownServerArguments(args) {
+		warnings.Warnf("server argument %q is not known to the oauth-server", name)
+	}
+	return warnings, nil
+}
+
+// validateServerArgumentPaths returns an error listing the file paths of the
+// server arguments that are not provided by any volume mounted to the container,
+// as the oauth-server would fail to start with such arguments. The validation
+// is bypassed together with the unknown server argument warnings. The audit policy file is
+// not checked when the audit configmap is not managed by the operator, its
+// path is provided by the user then (audit.policyFile).
+func validateServerArgumentPaths(
+	container *corev1.Container,
+	unsupportedConfig map[string]interface{},
+	args arguments.ServerArguments,
+) error {
+	skip, err := skipServerArgumentsValidation(unsupportedConfig)
+	if err != nil || skip {
+		return err
+	}
+
+	auditConfigMapManaged, err := observeoauth.AuditConfigMapManaged(unsupportedConfig)
+	if err != nil {
+		return err
+	}
+
+	unmounted := []string{}
+	for _, name := range fileServerArguments {
+		if name == "audit-policy-file" && !auditConfigMapManaged {
+			continue
+		}
+		for _, value := range args[name] {
+			// audit-log-path "-" means stdout
+			if name == "audit-log-path" && value == "-" {
+				continue
+			}
+			if !isMounted(container.VolumeMounts, value) {
+				unmounted = append(unmounted, fmt.Sprintf("%s=%s", name, value))
+			}
+		}
+	}
+
+	if len(unmounted) > 0 {
+		return fmt.Errorf("server arguments reference paths that no volume is mounted at: %s", strings.Join(unmounted, ", "))
+	}
+	return nil
+}
+
+// isMounted returns whether the file at filePath is provided by one of the mounts
+func isMounted(mounts []corev1.VolumeMount, filePath string) bool {
+	filePath = path.Clean(filePath)
+	for _, mount := range mounts {
+		mountPath := path.Clean(mount.MountPath)
+		if filePath == mountPath || strings.HasPrefix(filePath, strings.TrimSuffix(mountPath, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAuditPolicyFile makes sure that the audit-policy-file server argument
+// points at the audit policy key of the audit configmap when the file is
+// provided by the volume of that configmap, as a mismatch between the file name
+// and the configmap key leaves the oauth-server without its policy. The keys
+// of an audit configmap that is not managed by the operator are up to the
+// user, the validation is skipped then as well as together with the unknown
+// server argument warnings.
+func validateAuditPolicyFile(podSpec *corev1.PodSpec, container *corev1.Container, unsupportedConfig map[string]interface{}, args arguments.ServerArguments) error {
+	skip, err := skipServerArgumentsValidation(unsupportedConfig)
+	if err != nil || skip {
+		return err
+	}
+
+	auditConfigMapManaged, err := observeoauth.AuditConfigMapManaged(unsupportedConfig)
+	if err != nil || !auditConfigMapManaged {
+		return err
+	}
+
+	policyFiles := args["audit-policy-file"]
+	if len(policyFiles) == 0 {
+		return nil
+	}
+	policyFile := path.Clean(policyFiles[0])
+
+	for _, mount := range container.VolumeMounts {
+		mountPath := path.Clean(mount.MountPath)
+		if !strings.HasPrefix(policyFile, mountPath+"/") {
+			continue
+		}
+
+		for _, volume := range podSpec.Volumes {
+			if volume.Name != mount.Name || volume.ConfigMap == nil || volume.ConfigMap.Name != observeoauth.AuditPolicyConfigMapName {
+				continue
+			}
+
+			expected := path.Join(mountPath, observeoauth.AuditPolicyKey)
+			for _, item := range volume.ConfigMap.Items {
+				if item.Key == observeoauth.AuditPolicyKey {
+					expected = path.Join(mountPath, item.Path)
+				}
+			}
+
+			if policyFile != expected {
+				return fmt.Errorf("audit-policy-file %q does not match the %q key of the %q configmap mounted at %q, expected %q",
+					policyFile, observeoauth.AuditPolicyKey, observeoauth.AuditPolicyConfigMapName, mount.MountPath, expected)
+			}
+		}
+	}
+
+	return nil
+}
+
+// auditWebhookThrottle validates the throttle server arguments of the webhook
+// audit backend observed from audit.webhook in unsupportedConfigOverrides.operator. They
+// must be positive numbers so that audit events do not flood the receiving
+// webhook, and they are removed when the backend is not enabled.
+func auditWebhookThrottle(args arguments.ServerArguments) error {
+	if len(args["audit-webhook-config-file"]) == 0 {
+		for _, name := range auditWebhookThrottleArguments {
+			delete(args, name)
+		}
+		return nil
+	}
+
+	for _, value := range args["audit-webhook-batch-throttle-qps"] {
+		if qps, err := strconv.ParseFloat(value, 32); err != nil || qps <= 0 {
+			return fmt.Errorf("audit-webhook-batch-throttle-qps must be a positive number, got %q", value)
+		}
+	}
+	for _, value := range args["audit-webhook-batch-throttle-burst"] {
+		if burst, err := strconv.Atoi(value); err != nil || burst <= 0 {
+			return fmt.Errorf("audit-webhook-batch-throttle-burst must be a positive integer, got %q", value)
+		}
+	}
+
+	return nil
+}
+
+func skipServerArgumentsValidation(unsupportedConfig map[string]interface{}) (bool, error) {
+	skip, _, err := unstructured.NestedBool(unsupportedConfig, skipServerArgumentsValidationPath...)
+	if err != nil {
+		return false, fmt.Errorf("unable to read %s: %w", strings.Join(skipServerArgumentsValidationPath, "."), err)
+	}
+	return skip, nil
+}
@@ -0,0 +1,240 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common/arguments"
+)
+
+func TestValidateServerArgumentPaths(t *testing.T) {
+	container := &corev1.Container{
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "audit-policies", MountPath: "/var/run/configmaps/audit"},
+			{Name: "audit-dir", MountPath: "/var/log/oauth-server/"},
+		},
+	}
+
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig map[string]interface{}
+		args              arguments.ServerArguments
+		expectErr         bool
+	}{
+		{
+			name: "consistent",
+			args: arguments.ServerArguments{
+				"audit-log-path":    {"/var/log/oauth-server/audit.log"},
+				"audit-policy-file": {"/var/run/configmaps/audit/audit.yaml"},
+				"audit-log-format":  {"json"},
+			},
+		},
+		{
+			name: "audit log to stdout",
+			args: arguments.ServerArguments{
+				"audit-log-path": {"-"},
+			},
+		},
+		{
+			name: "policy file not mounted",
+			args: arguments.ServerArguments{
+				"audit-log-path":    {"/var/log/oauth-server/audit.log"},
+				"audit-policy-file": {"/var/run/configmaps/audit-custom/audit.yaml"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "path escaping the mount",
+			args: arguments.ServerArguments{
+				"audit-policy-file": {"/var/run/configmaps/audit/../policy.yaml"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "bypass",
+			unsupportedConfig: map[string]interface{}{
+				"deployment": map[string]interface{}{
+					"skipServerArgumentsValidation": true,
+				},
+			},
+			args: arguments.ServerArguments{
+				"audit-webhook-config-file": {"/etc/webhook/kubeconfig"},
+			},
+		},
+		{
+			name: "user-managed policy file",
+			unsupportedConfig: map[string]interface{}{
+				"audit": map[string]interface{}{
+					"manageConfigMap": false,
+					"policyFile":      "/etc/audit/policy.yaml",
+				},
+			},
+			args: arguments.ServerArguments{
+				"audit-log-path":    {"/var/log/oauth-server/audit.log"},
+				"audit-policy-file": {"/etc/audit/policy.yaml"},
+			},
+		},
+		{
+			name: "user-managed policy file does not exempt other paths",
+			unsupportedConfig: map[string]interface{}{
+				"audit": map[string]interface{}{
+					"manageConfigMap": false,
+				},
+			},
+			args: arguments.ServerArguments{
+				"audit-log-path":    {"/var/log/oauth-server-custom/audit.log"},
+				"audit-policy-file": {"/etc/audit/policy.yaml"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "policy file of a managed configmap not mounted",
+			unsupportedConfig: map[string]interface{}{
+				"audit": map[string]interface{}{
+					"manageConfigMap": true,
+				},
+			},
+			args: arguments.ServerArguments{
+				"audit-policy-file": {"/etc/audit/policy.yaml"},
+			},
+			expectErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateServerArgumentPaths(container, tt.unsupportedConfig, tt.args)
+			if tt.expectErr != (err != nil) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAuditPolicyFile(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		Volumes: []corev1.Volume{
+			{
+				Name: "audit-policies",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "audit"}},
+				},
+			},
+		},
+	}
+	container := &corev1.Container{
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "audit-policies", MountPath: "/var/run/configmaps/audit"},
+		},
+	}
+
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig map[string]interface{}
+		policyFile        string
+		expectErr         bool
+	}{
+		{
+			name:       "matching key",
+			policyFile: "/var/run/configmaps/audit/audit.yaml",
+		},
+		{
+			name:       "mismatching key",
+			policyFile: "/var/run/configmaps/audit/policy.yaml",
+			expectErr:  true,
+		},
+		{
+			name:       "not provided by the audit configmap",
+			policyFile: "/etc/audit/policy.yaml",
+		},
+		{
+			name: "key of an unmanaged configmap",
+			unsupportedConfig: map[string]interface{}{
+				"audit": map[string]interface{}{
+					"manageConfigMap": false,
+				},
+			},
+			policyFile: "/var/run/configmaps/audit/external.yaml",
+		},
+		{
+			name: "bypass",
+			unsupportedConfig: map[string]interface{}{
+				"deployment": map[string]interface{}{
+					"skipServerArgumentsValidation": true,
+				},
+			},
+			policyFile: "/var/run/configmaps/audit/policy.yaml",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAuditPolicyFile(podSpec, container, tt.unsupportedConfig, arguments.ServerArguments{"audit-policy-file": {tt.policyFile}})
+			if tt.expectErr != (err != nil) {
+				t.Errorf("expected error: %t, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestAuditWebhookThrottle(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		args         arguments.ServerArguments
+		expectedArgs arguments.ServerArguments
+		expectErr    bool
+	}{
+		{
+			name: "valid throttle with webhook enabled",
+			args: arguments.ServerArguments{
+				"audit-webhook-config-file":          {"/etc/webhook/kubeconfig"},
+				"audit-webhook-batch-throttle-qps":   {"10.5"},
+				"audit-webhook-batch-throttle-burst": {"15"},
+			},
+			expectedArgs: arguments.ServerArguments{
+				"audit-webhook-config-file":          {"/etc/webhook/kubeconfig"},
+				"audit-webhook-batch-throttle-qps":   {"10.5"},
+				"audit-webhook-batch-throttle-burst": {"15"},
+			},
+		},
+		{
+			name: "throttle omitted with webhook disabled",
+			args: arguments.ServerArguments{
+				"audit-log-path":                     {"/var/log/oauth-server/audit.log"},
+				"audit-webhook-batch-throttle-qps":   {"10"},
+				"audit-webhook-batch-throttle-burst": {"-1"},
+			},
+			expectedArgs: arguments.ServerArguments{
+				"audit-log-path": {"/var/log/oauth-server/audit.log"},
+			},
+		},
+		{
+			name: "zero qps",
+			args: arguments.ServerArguments{
+				"audit-webhook-config-file":        {"/etc/webhook/kubeconfig"},
+				"audit-webhook-batch-throttle-qps": {"0"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "non-integer burst",
+			args: arguments.ServerArguments{
+				"audit-webhook-config-file":          {"/etc/webhook/kubeconfig"},
+				"audit-webhook-batch-throttle-burst": {"1.5"},
+			},
+			expectErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := auditWebhookThrottle(tt.args)
+			if tt.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.expectedArgs, tt.args); diff != "" {
+				t.Errorf("unexpected server arguments (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package networkpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	networkingv1client "k8s.io/client-go/kubernetes/typed/networking/v1"
+	networkingv1listers "k8s.io/client-go/listers/networking/v1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/common"
+)
+
+const (
+	networkPolicyName      = "oauth-openshift"
+	networkPolicyNamespace = "openshift-authentication"
+
+	// managedByLabel marks the network policy as created by the operator, a
+	// network policy of the same name without it is never deleted
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "cluster-authentication-operator"
+)
+
+// knownConditionNames lists all condition types used by this controller.
+// These conditions are operated and defaulted by this controller.
+// Any new condition used by this controller sync() loop should be listed here.
+var knownConditionNames = sets.NewString(
+	"OAuthServerNetworkPolicyDegraded",
+)
+
+var networkPolicyEnabledPath = []string{"networkPolicy", "enabled"}
+
+// networkPolicyController restricts the ingress to the oauth-server pods to the
+// router, the host network (where the kube-apiserver runs), the operator and
+// the monitoring stack. It is turned on via unsupportedConfigOverrides.operator
+// (networkPolicy.enabled); the policy is removed again once it is turned off,
+// unless it was not created by the operator.
+type networkPolicyController struct {
+	operatorClient      v1helpers.OperatorClient
+	networkPolicies     networkingv1client.NetworkPoliciesGetter
+	networkPolicyLister networkingv1listers.NetworkPolicyLister
+}
+
+func NewNetworkPolicyController(
+	operatorClient v1helpers.OperatorClient,
+	kubeInformersForTargetNamespace informers.SharedInformerFactory,
+	networkPolicies networkingv1client.NetworkPoliciesGetter,
+	recorder events.Recorder,
+) factory.Controller {
+	c := &networkPolicyController{
+		operatorClient:      operatorClient,
+		networkPolicies:     networkPolicies,
+		networkPolicyLister: kubeInformersForTargetNamespace.Networking().V1().NetworkPolicies().Lister(),
+	}
+
+	return factory.New().WithInformers(
+		operatorClient.Informer(),
+		kubeInformersForTargetNamespace.Networking().V1().NetworkPolicies().Informer(),
+	).ResyncEvery(wait.Jitter(time.Minute, 1.0)).WithSync(c.sync).ToController("OAuthServerNetworkPolicy", recorder.WithComponentSuffix("oauth-server-network-policy-controller"))
+}
+
+func (c *networkPolicyController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	operatorSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+
+	foundConditions := []operatorv1.OperatorCondition{}
+
+	enabled, err := networkPolicyEnabled(operatorSpec)
+	if err != nil {
+		foundConditions = append(foundConditions, operatorv1.OperatorCondition{
+			Type:    "OAuthServerNetworkPolicyDegraded",
+			Status:  operatorv1.ConditionTrue,
+			Reason:  "InvalidConfig",
+			Message: err.Error(),
+		})
+	} else if enabled {
+		if err := c.ensureNetworkPolicy(ctx, syncCtx.Recorder()); err != nil {
+			foundConditions = append(foundConditions, operatorv1.OperatorCondition{
+				Type:    "OAuthServerNetworkPolicyDegraded",
+				Status:  operatorv1.ConditionTrue,
+				Reason:  "ApplyFailed",
+				Message: fmt.Sprintf("Failed to apply the network policy: %v", err),
+			})
+		}
+	} else if err := c.removeNetworkPolicy(ctx, syncCtx.Recorder()); err != nil {
+		foundConditions = append(foundConditions, operatorv1.OperatorCondition{
+			Type:    "OAuthServerNetworkPolicyDegraded",
+			Status:  operatorv1.ConditionTrue,
+			Reason:  "DeleteFailed",
+			Message: fmt.Sprintf("Failed to delete the network policy: %v", err),
+		})
+	}
+
+	return common.UpdateControllerConditions(ctx, c.operatorClient, knownConditionNames, foundConditions)
+}
+
+func networkPolicyEnabled(operatorSpec *operatorv1.OperatorSpec) (bool, error) {
+	unsupportedConfig, err := common.UnsupportedConfigOverrides(operatorSpec)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode unsupportedConfigOverrides: %w", err)
+	}
+
+	enabled, _, err := unstructured.NestedBool(unsupportedConfig, networkPolicyEnabledPath...)
+	if err != nil {
+		return false, fmt.Errorf("unable to read %s: %w", strings.Join(networkPolicyEnabledPath, "."), err)
+	}
+	return enabled, nil
+}
+
+// ensureNetworkPolicy creates the network policy or reverts any drift of its spec
+func (c *networkPolicyController) ensureNetworkPolicy(ctx context.Context, recorder events.Recorder) error {
+	required := expectedNetworkPolicy()
+
+	existing, err := c.networkPolicyLister.NetworkPolicies(networkPolicyNamespace).Get(networkPolicyName)
+	if errors.IsNotFound(err) {
+		if _, err := c.networkPolicies.NetworkPolicies(networkPolicyNamespace).Create(ctx, required, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		recorder.Eventf("NetworkPolicyCreated", "Created NetworkPolicy %s/%s", networkPolicyNamespace, networkPolicyName)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	labelsMatch := true
+	for k, v := range required.Labels {
+		if existing.Labels[k] != v {
+			labelsMatch = false
+		}
+	}
+	if labelsMatch && equality.Semantic.DeepEqual(existing.Spec, required.Spec) {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	for k, v := range required.Labels {
+		updated.Labels[k] = v
+	}
+	updated.Spec = required.Spec
+
+	if _, err := c.networkPolicies.NetworkPolicies(networkPolicyNamespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	recorder.Eventf("NetworkPolicyUpdated", "Updated NetworkPolicy %s/%s", networkPolicyNamespace, networkPolicyName)
+	return nil
+}
+
+// removeNetworkPolicy deletes the network policy if it was created by the operator
+func (c *networkPolicyController) removeNetworkPolicy(ctx context.Context, recorder events.Recorder) error {
+	existing, err := c.networkPolicyLister.NetworkPolicies(networkPolicyNamespace).Get(networkPolicyName)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if existing.Labels[managedByLabel] != managedByValue {
+		return nil
+	}
+
+	// only delete the very network policy that was checked for the label
+	preconditions := metav1.Preconditions{UID: &existing.UID}
+	if err := c.networkPolicies.NetworkPolicies(networkPolicyNamespace).Delete(ctx, networkPolicyName, metav1.DeleteOptions{Preconditions: &preconditions}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	recorder.Eventf("NetworkPolicyDeleted", "Deleted NetworkPolicy %s/%s", networkPolicyNamespace, networkPolicyName)
+	return nil
+}
+
+func expectedNetworkPolicy() *networkingv1.NetworkPolicy {
+	port := intstr.FromInt(6443)
+	protocol := corev1.ProtocolTCP
+
+	namespaceSelector := func(labels map[string]string) networkingv1.NetworkPolicyPeer {
+		return networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: labels},
+		}
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      networkPolicyName,
+			Namespace: networkPolicyNamespace,
+			Labels: map[string]string{
+				"app":          "oauth-openshift",
+				managedByLabel: managedByValue,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": "oauth-openshift",
+				},
+			},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					Ports: []networkingv1.NetworkPolicyPort{
+						{Protocol: &protocol, Port: &port},
+					},
+					From: []networkingv1.NetworkPolicyPeer{
+						// the router
+						namespaceSelector(map[string]string{"network.openshift.io/policy-group": "ingress"}),
+						// host network, e.g. the kube-apiserver and host network routers
+						namespaceSelector(map[string]string{"policy-group.network.openshift.io/host-network": ""}),
+						// the operator's health checks
+						namespaceSelector(map[string]string{"kubernetes.io/metadata.name": "openshift-authentication-operator"}),
+						// metrics scraping
+						namespaceSelector(map[string]string{"network.openshift.io/policy-group": "monitoring"}),
+					},
+				},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+}
@@ -0,0 +1,133 @@
+package networkpolicy
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	networkingv1listers "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/tools/cache"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/cluster-authentication-operator/bindata"
+)
+
+func TestNetworkPolicyController(t *testing.T) {
+	drifted := expectedNetworkPolicy()
+	drifted.Spec.Ingress = nil
+
+	unowned := expectedNetworkPolicy()
+	delete(unowned.Labels, managedByLabel)
+	unowned.Spec.Ingress = nil
+
+	for _, tt := range []struct {
+		name              string
+		unsupportedConfig string
+		existing          *networkingv1.NetworkPolicy
+		expectPolicy      bool
+		expectedSpec      *networkingv1.NetworkPolicySpec
+	}{
+		{
+			name: "disabled by default",
+		},
+		{
+			name:              "created when enabled",
+			unsupportedConfig: `{"operator":{"networkPolicy":{"enabled":true}}}`,
+			expectPolicy:      true,
+		},
+		{
+			name:              "drift is corrected",
+			unsupportedConfig: `{"operator":{"networkPolicy":{"enabled":true}}}`,
+			existing:          drifted,
+			expectPolicy:      true,
+		},
+		{
+			name:     "removed when disabled",
+			existing: expectedNetworkPolicy(),
+		},
+		{
+			name:         "unowned policy is left alone when disabled",
+			existing:     unowned,
+			expectPolicy: true,
+			expectedSpec: &unowned.Spec,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			operatorClient := v1helpers.NewFakeOperatorClient(&operatorv1.OperatorSpec{
+				ManagementState:            operatorv1.Managed,
+				UnsupportedConfigOverrides: runtime.RawExtension{Raw: []byte(tt.unsupportedConfig)},
+			}, &operatorv1.OperatorStatus{}, nil)
+
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			objects := []runtime.Object{}
+			if tt.existing != nil {
+				if err := indexer.Add(tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				objects = append(objects, tt.existing)
+			}
+			kubeClient := fake.NewSimpleClientset(objects...)
+
+			c := &networkPolicyController{
+				operatorClient:      operatorClient,
+				networkPolicies:     kubeClient.NetworkingV1(),
+				networkPolicyLister: networkingv1listers.NewNetworkPolicyLister(indexer),
+			}
+
+			if err := c.sync(context.TODO(), factory.NewSyncContext("testctx", events.NewInMemoryRecorder(t.Name()))); err != nil {
+				t.Fatal(err)
+			}
+
+			_, status, _, _ := operatorClient.GetOperatorState()
+			if condition := v1helpers.FindOperatorCondition(status.Conditions, "OAuthServerNetworkPolicyDegraded"); condition == nil || condition.Status != operatorv1.ConditionFalse {
+				t.Errorf("expected the controller to not be degraded, got %v", condition)
+			}
+
+			policy, err := kubeClient.NetworkingV1().NetworkPolicies("openshift-authentication").Get(context.TODO(), "oauth-openshift", metav1.GetOptions{})
+			if !tt.expectPolicy {
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected no network policy, got %v, %v", policy, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedSpec := expectedNetworkPolicy().Spec
+			if tt.expectedSpec != nil {
+				expectedSpec = *tt.expectedSpec
+			}
+			if !equality.Semantic.DeepEqual(expectedSpec, policy.Spec) {
+				t.Errorf("unexpected network policy spec: %v", policy.Spec)
+			}
+		})
+	}
+}
+
+func TestNetworkPolicySelectsOAuthServerPods(t *testing.T) {
+	deployment := resourceread.ReadDeploymentV1OrDie(bindata.MustAsset("oauth-openshift/deployment.yaml"))
+
+	selector, err := metav1.LabelSelectorAsSelector(&expectedNetworkPolicy().Spec.PodSelector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if selector.Empty() {
+		t.Fatal("expected the network policy to select specific pods only")
+	}
+	if !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+		t.Errorf("expected the network policy selector %q to match the oauth-server pods %v", selector, deployment.Spec.Template.Labels)
+	}
+}
@@ -18,6 +18,10 @@ type ConfigSyncData struct {
 	// data maps dest -> source
 	// dest is metadata.name for resource in our deployment's namespace
 	data map[string]sourceData
+
+	// idpNames maps the index of an IdP to its name for error reporting,
+	// it is not serialized
+	idpNames map[int]string
 }
 
 type ResourceType string
@@ -32,6 +36,9 @@ type sourceData struct {
 	MountPath string       `json:"mountPath"` // the mount path that this source is mapped to
 	Key       string       `json:"key"`
 	Type      ResourceType `json:"type"`
+
+	// idpIndex is the index of the IdP that references the source
+	idpIndex int
 }
 
 func HandleIdPConfigSync(resourceSyncer resourcesynccontroller.ResourceSyncer, oldData, newData *ConfigSyncData) {
@@ -87,12 +94,14 @@ func newSourceDataIDP(index int, resourceType ResourceType, resourceName, field,
 		MountPath: dirPath,
 		Key:       key,
 		Type:      resourceType,
+		idpIndex:  index,
 	}
 }
 
 func NewConfigSyncData() *ConfigSyncData {
 	return &ConfigSyncData{
-		data: map[string]sourceData{},
+		data:     map[string]sourceData{},
+		idpNames: map[int]string{},
 	}
 }
 
@@ -103,7 +112,7 @@ func NewConfigSyncDataFromJSON(jsBytes []byte) (*ConfigSyncData, error) {
 			return nil, fmt.Errorf("%s: %v", jsBytes, err)
 		}
 	}
-	return &ConfigSyncData{data: data}, nil
+	return &ConfigSyncData{data: data, idpNames: map[int]string{}}, nil
 }
 
 // Bytes returns JSON representation of the structure's internal data map
@@ -118,15 +127,32 @@ func (sd *ConfigSyncData) Validate(cmLister corelistersv1.ConfigMapLister, secre
 	for _, src := range sd.data {
 		if src.Type == SecretType {
 			if secretErrs := validateSecret(secretsLister, src); len(secretErrs) > 0 {
-				errs = append(errs, fmt.Errorf("error validating secret openshift-config/%s: %w", src.Name, errors.NewAggregate(secretErrs)))
+				errs = append(errs, sd.idpError(src, fmt.Errorf("error validating secret openshift-config/%s: %w", src.Name, errors.NewAggregate(secretErrs))))
 			}
 		} else if cmErrs := validateConfigMap(cmLister, src); len(cmErrs) > 0 {
-			errs = append(errs, fmt.Errorf("error validating configMap openshift-config/%s: %w", src.Name, errors.NewAggregate(cmErrs)))
+			errs = append(errs, sd.idpError(src, fmt.Errorf("error validating configMap openshift-config/%s: %w", src.Name, errors.NewAggregate(cmErrs))))
 		}
 	}
 	return errs
 }
 
+// SetIDPName records the name of the IdP at the given index so that validation
+// errors can name the IdP referencing the invalid resource
+func (sd *ConfigSyncData) SetIDPName(index int, name string) {
+	if sd.idpNames == nil {
+		sd.idpNames = map[int]string{}
+	}
+	sd.idpNames[index] = name
+}
+
+// idpError prefixes err with the name of the IdP referencing src, if known
+func (sd *ConfigSyncData) idpError(src sourceData, err error) error {
+	if name, ok := sd.idpNames[src.idpIndex]; ok {
+		return fmt.Errorf("identity provider %q: %w", name, err)
+	}
+	return err
+}
+
 // AddIDPSecret initializes a sourceData object with proper data for a Secret
 // and adds it among the other secrets stored here
 // Returns the path for the Secret
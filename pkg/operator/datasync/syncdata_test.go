@@ -0,0 +1,68 @@
+package datasync
+
+import (
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/library-go/pkg/crypto"
+	corev1 "k8s.io/api/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestConfigSyncDataValidateIDPKeys(t *testing.T) {
+	ca, err := crypto.MakeSelfSignedCAConfig("test-ca", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caPEM, _, err := ca.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name          string
+		configMap     *corev1.ConfigMap
+		expectedError []string
+	}{
+		{
+			name:      "key present",
+			configMap: testConfigMap("ldap-ca", map[string]string{corev1.ServiceAccountRootCAKey: string(caPEM)}),
+		},
+		{
+			name:          "key missing",
+			configMap:     testConfigMap("ldap-ca", map[string]string{"ca.pem": string(caPEM)}),
+			expectedError: []string{`identity provider "my-ldap"`, "openshift-config/ldap-ca", `missing required key: "ca.crt"`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(tt.configMap); err != nil {
+				t.Fatal(err)
+			}
+
+			syncData := NewConfigSyncData()
+			syncData.SetIDPName(0, "my-htpasswd")
+			syncData.SetIDPName(1, "my-ldap")
+			syncData.AddIDPConfigMap(1, configv1.ConfigMapNameReference{Name: "ldap-ca"}, "ca", corev1.ServiceAccountRootCAKey)
+
+			errs := syncData.Validate(corev1listers.NewConfigMapLister(indexer), corev1listers.NewSecretLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})))
+			if len(tt.expectedError) == 0 {
+				if len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+				}
+				return
+			}
+
+			if len(errs) != 1 {
+				t.Fatalf("expected a single error, got %v", errs)
+			}
+			for _, part := range tt.expectedError {
+				if !strings.Contains(errs[0].Error(), part) {
+					t.Errorf("expected error %q to contain %q", errs[0], part)
+				}
+			}
+		})
+	}
+}
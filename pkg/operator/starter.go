@@ -63,12 +63,14 @@ import (
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 
 	"github.com/openshift/cluster-authentication-operator/bindata"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/auditpolicy"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/configobservation/configobservercontroller"
 	componentroutesecretsync "github.com/openshift/cluster-authentication-operator/pkg/controllers/customroute"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/deployment"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/ingressnodesavailable"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/ingressstate"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/metadata"
+	"github.com/openshift/cluster-authentication-operator/pkg/controllers/networkpolicy"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/oauthclientscontroller"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/oauthendpoints"
 	"github.com/openshift/cluster-authentication-operator/pkg/controllers/payload"
@@ -275,7 +277,6 @@ func prepareOauthOperator(ctx context.Context, controllerContext *controllercmd.
 		"OpenshiftAuthenticationStaticResources",
 		bindata.Asset,
 		[]string{
-			"oauth-openshift/audit-policy.yaml",
 			"oauth-openshift/ns.yaml",
 			"oauth-openshift/authentication-clusterrolebinding.yaml",
 			"oauth-openshift/cabundle.yaml",
@@ -294,6 +295,7 @@ func prepareOauthOperator(ctx context.Context, controllerContext *controllercmd.
 		operatorCtx.operatorClient,
 		operatorCtx.kubeInformersForNamespaces,
 		operatorCtx.operatorConfigInformer,
+		operatorCtx.operatorInformer,
 		operatorCtx.resourceSyncController,
 		enabledClusterCapabilities,
 		controllerContext.EventRecorder,
@@ -340,6 +342,20 @@ func prepareOauthOperator(ctx context.Context, controllerContext *controllercmd.
 		controllerContext.EventRecorder,
 	)
 
+	auditPolicyController := auditpolicy.NewAuditPolicyController(
+		operatorCtx.operatorClient,
+		operatorCtx.kubeInformersForNamespaces,
+		operatorCtx.kubeClient.CoreV1(),
+		controllerContext.EventRecorder,
+	)
+
+	networkPolicyController := networkpolicy.NewNetworkPolicyController(
+		operatorCtx.operatorClient,
+		operatorCtx.kubeInformersForNamespaces.InformersFor("openshift-authentication"),
+		operatorCtx.kubeClient.NetworkingV1(),
+		controllerContext.EventRecorder,
+	)
+
 	serviceCAController := serviceca.NewServiceCAController(
 		operatorCtx.kubeInformersForNamespaces.InformersFor("openshift-authentication"),
 		operatorCtx.operatorConfigInformer,
@@ -483,6 +499,8 @@ func prepareOauthOperator(ctx context.Context, controllerContext *controllercmd.
 		payloadConfigController.Run,
 		routerCertsController.Run,
 		serviceCAController.Run,
+		auditPolicyController.Run,
+		networkPolicyController.Run,
 		staticResourceController.Run,
 		wellKnownReadyController.Run,
 		authRouteCheckController.Run,